@@ -0,0 +1,222 @@
+package acl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AccessRead is read access to a resource
+	AccessRead = "read"
+
+	// AccessWrite is write access to a resource
+	AccessWrite = "write"
+
+	// AccessList is list access to a key prefix
+	AccessList = "list"
+)
+
+// Definitions is a set of ACL definitions loaded for offline inspection
+type Definitions struct {
+	lib *definitionLibrary
+}
+
+// AccessInput is the input for the Can and WhoCan functions
+type AccessInput struct {
+	// Access is one of AccessRead, AccessWrite or AccessList
+	Access string
+	// Resource is a resource type and segment, e.g. "key/app/config",
+	// or "operator" and "keyring" alone
+	Resource string
+	// DefaultPolicy is Consul's acl_default_policy, "allow" or "deny"
+	DefaultPolicy string
+}
+
+// AccessResult is the outcome of evaluating a single ACL's access to a resource
+type AccessResult struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// LoadDefinitions reads the ACL definitions under an SSM prefix
+func (c *ClientSet) LoadDefinitions(prefix string, pageSize int64) (*Definitions, error) {
+	prefix = ensureTrailingSlash(prefix)
+	if prefix == "" {
+		return nil, errors.New("ACLDefinitionPrefix is required")
+	}
+	params, err := c.getDefinitionParameters(prefix, pageSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get ACL definition parameters from prefix \"%s\"", prefix)
+	}
+	lib, err := newDefinitionLibrary(prefix, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Definitions{lib: lib}, nil
+}
+
+// LoadDefinitionDirectory reads the ACL definitions in a local directory
+func LoadDefinitionDirectory(dir string) (*Definitions, error) {
+	prefix, params, err := readDefinitionDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	lib, err := newDefinitionLibrary(prefix, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Definitions{lib: lib}, nil
+}
+
+// items returns the definitions not marked for destruction
+func (d *Definitions) items() ([]*aclItem, error) {
+	var acls []*aclItem
+	for _, param := range d.lib.definitions {
+		acl, err := d.lib.parseDefinition(param)
+		if err != nil {
+			return nil, err
+		}
+		if !acl.Destroy {
+			acls = append(acls, acl)
+		}
+	}
+	return acls, nil
+}
+
+// Can evaluates whether the ACL with the given slug has access to a resource
+func (d *Definitions) Can(slug string, i *AccessInput) (*AccessResult, error) {
+	acls, err := d.items()
+	if err != nil {
+		return nil, err
+	}
+	for _, acl := range acls {
+		if acl.slug == slug {
+			return evaluateAccess(acl, i)
+		}
+	}
+	return nil, errors.Errorf("No ACL definition found for \"%s\"", slug)
+}
+
+// WhoCan returns the ACLs with access to a resource
+func (d *Definitions) WhoCan(i *AccessInput) ([]AccessResult, error) {
+	acls, err := d.items()
+	if err != nil {
+		return nil, err
+	}
+	var results []AccessResult
+	for _, acl := range acls {
+		if strings.Contains(acl.Rules, "{{") {
+			log.Warnf("Skipping ACL %s (Name: \"%s\") - rules are templated.", acl.slug, acl.Name)
+			continue
+		}
+		result, err := evaluateAccess(acl, i)
+		if err != nil {
+			return nil, err
+		}
+		if result.Allowed {
+			results = append(results, *result)
+		}
+	}
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].Slug < results[b].Slug
+	})
+	return results, nil
+}
+
+// evaluateAccess evaluates an ACL's access to a resource using the
+// longest-prefix matching of Consul's legacy ACL system
+func evaluateAccess(acl *aclItem, i *AccessInput) (*AccessResult, error) {
+	resource, segment, err := parseResource(i.Resource, i.Access)
+	if err != nil {
+		return nil, err
+	}
+	if i.DefaultPolicy != "allow" && i.DefaultPolicy != "deny" {
+		return nil, errors.Errorf("Default policy must be \"allow\" or \"deny\", got \"%s\"", i.DefaultPolicy)
+	}
+
+	result := &AccessResult{Slug: acl.slug, Name: acl.Name, Type: acl.Type}
+	if acl.Type == "management" {
+		result.Allowed = true
+		result.Reason = "management ACL"
+		return result, nil
+	}
+	if strings.Contains(acl.Rules, "{{") {
+		return nil, errors.Errorf("Rules of ACL %s are templated and can't be evaluated", acl.slug)
+	}
+
+	rules, err := parseRules(acl.Rules)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse rules of ACL %s", acl.slug)
+	}
+
+	// the longest matching segment wins, and the last of equal ones
+	var match *aclRule
+	for n := range rules {
+		rule := rules[n]
+		if rule.Resource != resource || !strings.HasPrefix(segment, rule.Segment) {
+			continue
+		}
+		if match == nil || len(rule.Segment) >= len(match.Segment) {
+			match = &rule
+		}
+	}
+
+	if match == nil {
+		result.Allowed = i.DefaultPolicy == "allow"
+		result.Reason = fmt.Sprintf("no matching rule, default policy %s", i.DefaultPolicy)
+		return result, nil
+	}
+	result.Allowed = policyAllows(match.Policy, i.Access)
+	result.Reason = match.String()
+	return result, nil
+}
+
+// parseResource splits a resource into its type and segment
+func parseResource(resource, access string) (string, string, error) {
+	parts := strings.SplitN(resource, "/", 2)
+	if !contains(ruleResources, parts[0]) {
+		return "", "", errors.Errorf("Unknown resource type \"%s\"", parts[0])
+	}
+
+	switch access {
+	case AccessRead, AccessWrite:
+	case AccessList:
+		if parts[0] != "key" {
+			return "", "", errors.New("List access only applies to keys")
+		}
+	default:
+		return "", "", errors.Errorf("Access must be read, write or list, got \"%s\"", access)
+	}
+
+	if parts[0] == "keyring" || parts[0] == "operator" {
+		if len(parts) > 1 {
+			return "", "", errors.Errorf("Resource type \"%s\" has no segments", parts[0])
+		}
+		return parts[0], "", nil
+	}
+	if len(parts) == 1 {
+		return "", "", errors.Errorf("Resource must be given as %s/NAME", parts[0])
+	}
+	return parts[0], parts[1], nil
+}
+
+// policyAllows returns whether a rule's policy grants an access level
+func policyAllows(policy, access string) bool {
+	switch access {
+	case AccessRead:
+		return policy == policyRead || policy == policyWrite || policy == policyList
+	case AccessWrite:
+		return policy == policyWrite
+	case AccessList:
+		return policy == policyList || policy == policyWrite
+	default:
+		return false
+	}
+}
@@ -0,0 +1,175 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+)
+
+// testDefinitions returns Definitions loaded from parameters under testPrefix
+func testDefinitions(t *testing.T, values map[string]string) *Definitions {
+	lib, err := newDefinitionLibrary(testPrefix, testParams(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Definitions{lib: lib}
+}
+
+func TestEvaluateAccess(t *testing.T) {
+	rules := `
+key "" { policy = "read" }
+key "app/" { policy = "write" }
+key "app/secret/" { policy = "deny" }
+key "list/" { policy = "list" }
+service "web" { policy = "write" }
+service "" { policy = "read" }
+operator = "read"
+`
+	cases := []struct {
+		name     string
+		aclType  string
+		rules    string
+		access   string
+		resource string
+		policy   string
+		want     bool
+		reason   string
+		wantErr  string
+	}{
+		{name: "shortest prefix", access: AccessRead, resource: "key/other", want: true, reason: `key "" { policy = "read" }`},
+		{name: "shortest prefix write", access: AccessWrite, resource: "key/other", want: false, reason: `key "" { policy = "read" }`},
+		{name: "longer prefix", access: AccessWrite, resource: "key/app/config", want: true, reason: `key "app/" { policy = "write" }`},
+		{name: "longest prefix deny", access: AccessRead, resource: "key/app/secret/db", want: false, reason: `key "app/secret/" { policy = "deny" }`},
+		{name: "exact prefix", access: AccessRead, resource: "key/app/secret/", want: false, reason: `key "app/secret/" { policy = "deny" }`},
+		{name: "list", access: AccessList, resource: "key/list/a", want: true, reason: `key "list/" { policy = "list" }`},
+		{name: "list from write", access: AccessList, resource: "key/app/a", want: true},
+		{name: "list from read", access: AccessList, resource: "key/other", want: false},
+		{name: "read from list", access: AccessRead, resource: "key/list/a", want: true},
+		{name: "write from list", access: AccessWrite, resource: "key/list/a", want: false},
+		{name: "service", access: AccessWrite, resource: "service/web-api", want: true, reason: `service "web" { policy = "write" }`},
+		{name: "operator", access: AccessRead, resource: "operator", want: true, reason: `operator = "read"`},
+		{name: "default deny", access: AccessRead, resource: "node/db", want: false, reason: "no matching rule, default policy deny"},
+		{name: "default allow", access: AccessWrite, resource: "node/db", policy: "allow", want: true, reason: "no matching rule, default policy allow"},
+		{name: "default not used", access: AccessWrite, resource: "key/other", policy: "allow", want: false},
+		{
+			name:     "last of equal",
+			rules:    `key "a/" { policy = "read" } key "a/" { policy = "write" }`,
+			access:   AccessWrite,
+			resource: "key/a/b",
+			want:     true,
+		},
+		{
+			name:     "management",
+			aclType:  "management",
+			access:   AccessWrite,
+			resource: "keyring",
+			want:     true,
+			reason:   "management ACL",
+		},
+		{
+			name:     "templated",
+			rules:    `key "{{ .app }}/" { policy = "read" }`,
+			access:   AccessRead,
+			resource: "key/app",
+			wantErr:  "templated",
+		},
+		{
+			name:     "invalid rules",
+			rules:    `key "" { policy = "admin" }`,
+			access:   AccessRead,
+			resource: "key/app",
+			wantErr:  "Failed to parse rules",
+		},
+		{name: "unknown resource", access: AccessRead, resource: "acl/x", wantErr: "Unknown resource type"},
+		{name: "unknown access", access: "admin", resource: "key/x", wantErr: "Access must be"},
+		{name: "list on service", access: AccessList, resource: "service/web", wantErr: "only applies to keys"},
+		{name: "missing segment", access: AccessRead, resource: "key", wantErr: "key/NAME"},
+		{name: "operator segment", access: AccessRead, resource: "operator/x", wantErr: "has no segments"},
+		{name: "invalid default", access: AccessRead, resource: "key/x", policy: "maybe", wantErr: "Default policy"},
+	}
+
+	for _, tc := range cases {
+		acl := aclItem{}
+		acl.Type = tc.aclType
+		acl.Rules = tc.rules
+		if acl.Type == "" {
+			acl.Type = "client"
+		}
+		if acl.Rules == "" && acl.Type == "client" {
+			acl.Rules = rules
+		}
+		policy := tc.policy
+		if policy == "" {
+			policy = "deny"
+		}
+
+		result, err := evaluateAccess(&acl, &AccessInput{Access: tc.access, Resource: tc.resource, DefaultPolicy: policy})
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+		if result.Allowed != tc.want {
+			t.Errorf("%s: Allowed = %t, want %t (%s)", tc.name, result.Allowed, tc.want, result.Reason)
+		}
+		if tc.reason != "" && result.Reason != tc.reason {
+			t.Errorf("%s: Reason = %q, want %q", tc.name, result.Reason, tc.reason)
+		}
+	}
+}
+
+func TestCanAndWhoCan(t *testing.T) {
+	defs := testDefinitions(t, map[string]string{
+		"_fragments/read-kv": `key "" { policy = "read" }`,
+		"admin":              `{"Name": "Admin", "Type": "management"}`,
+		"app":                `{"Name": "App", "Include": ["read-kv"], "Rules": "key \"app/\" { policy = \"write\" }"}`,
+		"ops/_defaults":      `{"Rules": "operator = \"write\""}`,
+		"ops/oncall":         `{"Name": "On-call", "Include": ["read-kv"]}`,
+		"templated":          `{"Name": "Templated", "Rules": "key \"{{ .app }}/\" { policy = \"write\" }"}`,
+		"gone":               `{"Destroy": "true", "Type": "management"}`,
+	})
+
+	cases := []struct {
+		access   string
+		resource string
+		want     string
+	}{
+		{AccessRead, "key/app/config", "admin,app,oncall"},
+		{AccessWrite, "key/app/config", "admin,app"},
+		{AccessWrite, "key/other", "admin"},
+		{AccessWrite, "operator", "admin,oncall"},
+		{AccessRead, "node/db", "admin"},
+	}
+	for _, tc := range cases {
+		results, err := defs.WhoCan(&AccessInput{Access: tc.access, Resource: tc.resource, DefaultPolicy: "deny"})
+		if err != nil {
+			t.Errorf("WhoCan(%s %s): unexpected error: %s", tc.access, tc.resource, err)
+			continue
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Slug)
+		}
+		if strings.Join(got, ",") != tc.want {
+			t.Errorf("WhoCan(%s %s) = %s, want %s", tc.access, tc.resource, strings.Join(got, ","), tc.want)
+		}
+	}
+
+	result, err := defs.Can("oncall", &AccessInput{Access: AccessWrite, Resource: "operator", DefaultPolicy: "deny"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || result.Reason != `operator = "write"` {
+		t.Errorf("Can(oncall) = %+v", result)
+	}
+	if _, err := defs.Can("gone", &AccessInput{Access: AccessRead, Resource: "key/x", DefaultPolicy: "deny"}); err == nil {
+		t.Error("Can(gone): expected error for a definition marked for destruction")
+	}
+	if _, err := defs.Can("templated", &AccessInput{Access: AccessRead, Resource: "key/x", DefaultPolicy: "deny"}); err == nil {
+		t.Error("Can(templated): expected error for templated rules")
+	}
+}
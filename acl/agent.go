@@ -0,0 +1,48 @@
+package acl
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AgentACLToken is the agent's acl_token
+	AgentACLToken = "acl_token"
+
+	// AgentACLAgentToken is the agent's acl_agent_token
+	AgentACLAgentToken = "acl_agent_token"
+
+	// AgentACLAgentMasterToken is the agent's acl_agent_master_token
+	AgentACLAgentMasterToken = "acl_agent_master_token"
+
+	// AgentACLReplicationToken is the agent's acl_replication_token
+	AgentACLReplicationToken = "acl_replication_token"
+)
+
+// UpdateAgentTokenFromParameter sets one of the local Consul agent's ACL
+// tokens to the token ID stored in an SSM parameter
+func (c *ClientSet) UpdateAgentTokenFromParameter(tokenType, param string) error {
+	token, err := c.getStringParameter(c.ids, param, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get %s from SSM parameter \"%s\"", tokenType, param)
+	}
+	return c.UpdateAgentToken(tokenType, *token)
+}
+
+// UpdateAgentToken sets one of the local Consul agent's ACL tokens
+func (c *ClientSet) UpdateAgentToken(tokenType, token string) (err error) {
+	switch tokenType {
+	case AgentACLToken:
+		_, err = c.Consul.Agent().UpdateACLToken(token, nil)
+	case AgentACLAgentToken:
+		_, err = c.Consul.Agent().UpdateACLAgentToken(token, nil)
+	case AgentACLAgentMasterToken:
+		_, err = c.Consul.Agent().UpdateACLAgentMasterToken(token, nil)
+	case AgentACLReplicationToken:
+		_, err = c.Consul.Agent().UpdateACLReplicationToken(token, nil)
+	default:
+		err = fmt.Errorf("Unknown agent token type \"%s\"", tokenType)
+	}
+	return
+}
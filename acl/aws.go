@@ -0,0 +1,111 @@
+package acl
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultRegion is used when no region is configured or detected
+const defaultRegion = "us-east-1"
+
+// AWSInput holds optional AWS credential and endpoint settings,
+// which take precedence over the SDK's default configuration
+type AWSInput struct {
+	Region      string
+	Profile     string
+	RoleARN     string
+	ExternalID  string
+	SessionName string
+	SSMEndpoint string
+}
+
+// newSSMClient creates an SSM client from the given AWS settings, using
+// region when no region is configured
+func newSSMClient(i AWSInput, region string) (*ssm.SSM, error) {
+	sess, err := newAWSSession(i, region)
+	if err != nil {
+		return nil, err
+	}
+	return ssmClientFromSession(sess, i), nil
+}
+
+// newAWSSession creates an AWS session from the given AWS settings. When
+// no region is configured, region is used, or detected if that is empty.
+func newAWSSession(i AWSInput, region string) (*session.Session, error) {
+	opts := session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Profile:           i.Profile,
+	}
+	if i.Region != "" {
+		opts.Config.Region = aws.String(i.Region)
+	}
+
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	if aws.StringValue(sess.Config.Region) == "" {
+		if region == "" {
+			region = detectRegion(sess)
+		}
+		sess.Config.Region = aws.String(region)
+	}
+
+	if i.RoleARN != "" {
+		log.Debugf("Assuming role %s", i.RoleARN)
+		creds := stscreds.NewCredentials(sess, i.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if i.ExternalID != "" {
+				p.ExternalID = aws.String(i.ExternalID)
+			}
+			if i.SessionName != "" {
+				p.RoleSessionName = i.SessionName
+			}
+		})
+		sess = sess.Copy(&aws.Config{Credentials: creds})
+	}
+	return sess, nil
+}
+
+// ssmClientFromSession creates an SSM client using the given session
+func ssmClientFromSession(sess *session.Session, i AWSInput) *ssm.SSM {
+	cfg := aws.NewConfig()
+	if i.SSMEndpoint != "" {
+		cfg.Endpoint = aws.String(i.SSMEndpoint)
+	}
+	return ssm.New(sess, cfg)
+}
+
+// scopedSSMClient is a helper for NewClientSet and creates an SSM client from
+// the given settings, or returns the default client if there are none. The
+// default client's region is used when the settings configure none, so the
+// region is only detected once.
+func scopedSSMClient(defaultClient *ssm.SSM, i *AWSInput) (*ssm.SSM, error) {
+	if i == nil {
+		return defaultClient, nil
+	}
+	return newSSMClient(*i, aws.StringValue(defaultClient.Config.Region))
+}
+
+// detectRegion is a helper for newAWSSession and looks up the region from
+// EC2 instance metadata, falling back to the default region
+func detectRegion(sess *session.Session) string {
+	client := ec2metadata.New(sess, &aws.Config{
+		HTTPClient: &http.Client{Timeout: 2 * time.Second},
+		MaxRetries: aws.Int(0),
+	})
+	if region, err := client.Region(); err == nil && region != "" {
+		log.Debugf("Using AWS region %s from instance metadata", region)
+		return region
+	}
+	log.Warnf("AWS region not set and could not be detected, using %s", defaultRegion)
+	return defaultRegion
+}
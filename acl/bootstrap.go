@@ -1,7 +1,35 @@
 package acl
 
 import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// bootstrapNotAllowed is the error Consul returns once ACLs have been bootstrapped
+	bootstrapNotAllowed = "ACL bootstrap no longer allowed"
+
+	// bootstrapResetFile is the file in a server's data directory
+	// that Consul reads the bootstrap reset index from
+	bootstrapResetFile = "acl-bootstrap-reset"
+)
+
+// resetIndexRegexp matches the reset index in a bootstrap error
+var resetIndexRegexp = regexp.MustCompile(`reset index: (\d+)`)
+
+var (
+	// ErrAlreadyBootstrapped is returned by Bootstrap when Consul ACLs were
+	// previously bootstrapped and the SSM parameter holds a working management token
+	ErrAlreadyBootstrapped = errors.New("Consul ACLs already bootstrapped, SSM parameter holds a valid management token")
+
+	// ErrInvalidBootstrapToken is returned by Bootstrap when Consul ACLs were
+	// previously bootstrapped but the SSM parameter does not hold a working management token
+	ErrInvalidBootstrapToken = errors.New("Consul ACLs already bootstrapped, but SSM parameter does not hold a valid management token")
 )
 
 // Bootstrap performs a Consul ACL bootstrap and saves the resulting token to an SSM parameter
@@ -13,10 +41,89 @@ func (c *ClientSet) Bootstrap(consulTokenParam string) (string, error) {
 
 	id, _, err := c.Consul.ACL().Bootstrap()
 	if err != nil {
+		if strings.Contains(err.Error(), bootstrapNotAllowed) {
+			return "", c.checkBootstrapToken(consulTokenParam)
+		}
 		return "", errors.Wrap(err, "Bootstrap failed")
 	}
 
-	if err := c.PutStringParameter(consulTokenParam, id); err != nil {
+	if err := c.putStringParameter(c.tokens, consulTokenParam, id); err != nil {
+		return id, errors.Wrapf(err, "Bootstrap succeeded, but failed to save token SSM parameter to \"%s\"", consulTokenParam)
+	}
+
+	return id, nil
+}
+
+// checkBootstrapToken is a helper for Bootstrap and determines whether
+// the token already stored in the SSM parameter is a working management token
+func (c *ClientSet) checkBootstrapToken(consulTokenParam string) error {
+	val, err := c.getStringParameter(c.tokens, consulTokenParam, false)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get management token from SSM parameter \"%s\"", consulTokenParam)
+	}
+	if *val == "" {
+		log.Debugf("SSM parameter \"%s\" not found", consulTokenParam)
+		return ErrInvalidBootstrapToken
+	}
+
+	client, err := c.newConsulClient(*val)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create Consul client")
+	}
+
+	// look up the token with itself
+	if err := verifyManagementToken(client, *val); err != nil {
+		log.Debugf("Failed to verify token from SSM parameter \"%s\": %s", consulTokenParam, err.Error())
+		return ErrInvalidBootstrapToken
+	}
+
+	return ErrAlreadyBootstrapped
+}
+
+// BootstrapReset performs a Consul ACL bootstrap, resetting the bootstrap
+// state first if ACLs were already bootstrapped. The reset index from Consul's
+// response is written to the reset file in dataDir, which must be the data
+// directory of the current leader. The new token is saved to an SSM parameter.
+func (c *ClientSet) BootstrapReset(consulTokenParam, dataDir string) (string, error) {
+	if consulTokenParam == "" {
+		return "", errors.New("consulTokenParam cannot be empty")
+	}
+	if dataDir == "" {
+		return "", errors.New("dataDir cannot be empty")
+	}
+
+	id, _, err := c.Consul.ACL().Bootstrap()
+	if err != nil {
+		if !strings.Contains(err.Error(), bootstrapNotAllowed) {
+			return "", errors.Wrap(err, "Bootstrap failed")
+		}
+
+		match := resetIndexRegexp.FindStringSubmatch(err.Error())
+		if match == nil {
+			return "", errors.Wrap(err, "Bootstrap failed, Consul did not return a reset index")
+		}
+
+		isLeader, err := c.isLeader()
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to determine Consul leader")
+		}
+		if !isLeader {
+			return "", errors.New("Bootstrap reset must be run on the Consul leader")
+		}
+
+		resetFile := filepath.Join(dataDir, bootstrapResetFile)
+		log.Infof("Writing bootstrap reset index %s to %s", match[1], resetFile)
+		if err := ioutil.WriteFile(resetFile, []byte(match[1]), 0600); err != nil {
+			return "", errors.Wrap(err, "Failed to write bootstrap reset file")
+		}
+
+		id, _, err = c.Consul.ACL().Bootstrap()
+		if err != nil {
+			return "", errors.Wrap(err, "Bootstrap failed after reset")
+		}
+	}
+
+	if err := c.putStringParameter(c.tokens, consulTokenParam, id); err != nil {
 		return id, errors.Wrapf(err, "Bootstrap succeeded, but failed to save token SSM parameter to \"%s\"", consulTokenParam)
 	}
 
@@ -0,0 +1,200 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// breakGlassNamePrefix starts the name of every break-glass ACL,
+	// the name records the expiry so Consul alone is enough to revoke it
+	breakGlassNamePrefix = "Break Glass: expires "
+
+	// maxParameterDescriptionLength is the longest description SSM allows
+	maxParameterDescriptionLength = 1024
+)
+
+// BreakGlassInput is the input for the BreakGlass function
+type BreakGlassInput struct {
+	// Param is the SSM parameter the break-glass token is written to
+	Param string
+	TTL   time.Duration
+	// MaxTTL caps the TTL, zero means no cap
+	MaxTTL time.Duration
+	Reason string
+	// Requester defaults to the caller's AWS identity
+	Requester string
+}
+
+// BreakGlass creates a short-lived management ACL for emergency access,
+// writes its ID to an SSM parameter and returns it
+func (c *ClientSet) BreakGlass(i *BreakGlassInput) (string, error) {
+	if i.Param == "" {
+		return "", errors.New("Param cannot be empty")
+	}
+	if i.TTL <= 0 {
+		return "", errors.New("TTL must be positive")
+	}
+	if i.MaxTTL > 0 && i.TTL > i.MaxTTL {
+		return "", errors.Errorf("TTL %s exceeds the maximum of %s", i.TTL, i.MaxTTL)
+	}
+	if strings.TrimSpace(i.Reason) == "" {
+		return "", errors.New("Reason cannot be empty")
+	}
+
+	requester := i.Requester
+	if requester == "" {
+		identity, err := c.sts.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to determine requester from AWS identity")
+		}
+		requester = aws.StringValue(identity.Arn)
+	}
+
+	// only one break-glass token may be active per parameter
+	current, err := c.getStringParameter(c.tokens, i.Param, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get SSM parameter \"%s\"", i.Param)
+	}
+	if *current != "" {
+		currentACL, _, err := c.Consul.ACL().Info(*current, nil)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get info for current break-glass ACL")
+		}
+		if currentACL != nil {
+			if expires, ok := breakGlassExpiry(currentACL.Name); ok && time.Now().Before(expires) {
+				return "", errors.Errorf("A break-glass token expiring at %s is already stored in SSM parameter \"%s\"",
+					expires.Format(time.RFC3339), i.Param)
+			}
+		}
+	}
+
+	expires := time.Now().Add(i.TTL).UTC().Truncate(time.Second)
+	name := fmt.Sprintf("%s%s, requested by %s: %s", breakGlassNamePrefix, expires.Format(time.RFC3339), requester, i.Reason)
+
+	id, _, err := c.Consul.ACL().Create(&consulapi.ACLEntry{
+		Name: name,
+		Type: "management",
+	}, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create break-glass ACL")
+	}
+	log.Infof("Created break-glass ACL for %s, expiring at %s.", requester, expires.Format(time.RFC3339))
+
+	if err := c.putBreakGlassParameter(i.Param, id, name); err != nil {
+		if _, err := c.Consul.ACL().Destroy(id, nil); err != nil {
+			log.Errorf("Failed to clean up break-glass ACL: %s", err.Error())
+		}
+		return "", errors.Wrapf(err, "Failed to save break-glass token to SSM parameter \"%s\"", i.Param)
+	}
+
+	if _, err := c.tokens.AddTagsToResource(&ssm.AddTagsToResourceInput{
+		ResourceType: aws.String(tagResourceType),
+		ResourceId:   aws.String(i.Param),
+		Tags: []*ssm.Tag{
+			{Key: aws.String("Requester"), Value: aws.String(tagValue(requester))},
+			{Key: aws.String("Reason"), Value: aws.String(tagValue(i.Reason))},
+			{Key: aws.String("ExpiresAt"), Value: aws.String(expires.Format(time.RFC3339))},
+		},
+	}); err != nil {
+		log.Warnf("Failed to tag SSM parameter \"%s\": %s", i.Param, err.Error())
+	}
+
+	return id, nil
+}
+
+// putBreakGlassParameter is a helper for BreakGlass and writes the token,
+// always replacing a previous one, with the ACL name as description
+func (c *ClientSet) putBreakGlassParameter(param, id, description string) error {
+	if r := []rune(description); len(r) > maxParameterDescriptionLength {
+		description = string(r[:maxParameterDescriptionLength])
+	}
+	i := ssm.PutParameterInput{
+		Name:        aws.String(param),
+		Value:       aws.String(id),
+		Description: aws.String(description),
+		Overwrite:   aws.Bool(true),
+		Type:        aws.String("SecureString"),
+	}
+	if c.insecure {
+		i.Type = aws.String("String")
+	}
+	if c.kmsKeyID != "" {
+		i.KeyId = aws.String(c.kmsKeyID)
+	}
+	_, err := c.tokens.PutParameter(&i)
+	return err
+}
+
+// RevokeBreakGlass destroys break-glass ACLs, only expired ones if expiredOnly
+// is set, and deletes the given SSM parameter if its token was destroyed.
+// The number of destroyed ACLs is returned.
+func (c *ClientSet) RevokeBreakGlass(param string, expiredOnly bool) (int, error) {
+	acls, _, err := c.Consul.ACL().List(nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to list ACLs")
+	}
+
+	now := time.Now()
+	var destroyed []string
+	for _, entry := range acls {
+		expires, ok := breakGlassExpiry(entry.Name)
+		if !ok || entry.Type != "management" || (expiredOnly && now.Before(expires)) {
+			continue
+		}
+		log.Infof("Destroying break-glass ACL expiring at %s.", expires.Format(time.RFC3339))
+		if _, err := c.Consul.ACL().Destroy(entry.ID, nil); err != nil {
+			return len(destroyed), errors.Wrap(err, "Failed to destroy break-glass ACL")
+		}
+		destroyed = append(destroyed, entry.ID)
+	}
+
+	if param != "" {
+		current, err := c.getStringParameter(c.tokens, param, false)
+		if err != nil {
+			return len(destroyed), errors.Wrapf(err, "Failed to get SSM parameter \"%s\"", param)
+		}
+		// the token may also have been destroyed by other means
+		stale := contains(destroyed, *current)
+		if *current != "" && !stale {
+			currentACL, _, err := c.Consul.ACL().Info(*current, nil)
+			if err != nil {
+				return len(destroyed), errors.Wrap(err, "Failed to get info for current break-glass ACL")
+			}
+			stale = currentACL == nil
+		}
+		if *current != "" && stale {
+			log.Infof("Deleting break-glass token from SSM parameter \"%s\".", param)
+			if _, err := c.tokens.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(param)}); err != nil {
+				return len(destroyed), errors.Wrapf(err, "Failed to delete SSM parameter \"%s\"", param)
+			}
+		}
+	}
+
+	return len(destroyed), nil
+}
+
+// breakGlassExpiry returns the expiry recorded in the name of a break-glass ACL.
+// Definitions may not use the name prefix, so only break-glass ACLs match.
+func breakGlassExpiry(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, breakGlassNamePrefix) {
+		return time.Time{}, false
+	}
+	ts := strings.TrimPrefix(name, breakGlassNamePrefix)
+	if n := strings.Index(ts, ","); n >= 0 {
+		ts = ts[:n]
+	}
+	expires, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expires, true
+}
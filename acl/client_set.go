@@ -1,10 +1,14 @@
 package acl
 
 import (
+	"io/ioutil"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -12,11 +16,22 @@ import (
 
 // ClientSet represents a collection of clients
 type ClientSet struct {
-	SSM       *ssm.SSM
-	Consul    *consulapi.Client
-	kmsKeyID  string
-	overwrite bool
-	insecure  bool
+	SSM         *ssm.SSM
+	Consul      *consulapi.Client
+	definitions *ssm.SSM
+	ids         *ssm.SSM
+	tokens      *ssm.SSM
+	sts         *sts.STS
+	consulToken string
+	consulInput ConsulInput
+	kmsKeyID    string
+	overwrite   bool
+	insecure    bool
+	// metadataTagged caches the metadata last tagged on each ID
+	// parameter, so a recurring sync only re-tags on changes
+	metadataTagged map[string]taggedMetadata
+	// definitionsCreated caches the CreatedAt tags of ID parameters
+	definitionsCreated map[string]time.Time
 }
 
 // ClientSetInput is used as input for the NewClientSet function
@@ -25,30 +40,72 @@ type ClientSetInput struct {
 	KMSKeyID         string
 	Overwrite        bool
 	Insecure         bool
+	Consul           ConsulInput
+	AWS              AWSInput
+	// optional AWS settings for the definition prefix, ID prefix and
+	// management token parameter, each defaulting to AWS
+	DefinitionAWS *AWSInput
+	IDAWS         *AWSInput
+	TokenAWS      *AWSInput
+}
+
+// ConsulInput holds optional Consul connection settings, which take
+// precedence over the CONSUL_HTTP_* environment variables
+type ConsulInput struct {
+	Address            string
+	Scheme             string
+	Datacenter         string
+	TokenFile          string
+	CAFile             string
+	CAPath             string
+	CertFile           string
+	KeyFile            string
+	TLSServerName      string
+	InsecureSkipVerify bool
 }
 
 // NewClientSet creates a new client collection
 func NewClientSet(i *ClientSetInput) (*ClientSet, error) {
-	sess := session.Must(session.NewSession())
-	ssmClient := ssm.New(sess)
+	sess, err := newAWSSession(i.AWS, "")
+	if err != nil {
+		return nil, err
+	}
+	ssmClient := ssmClientFromSession(sess, i.AWS)
 
 	var c ClientSet
 	c.SSM = ssmClient
+	c.sts = sts.New(sess)
+	if c.definitions, err = scopedSSMClient(ssmClient, i.DefinitionAWS); err != nil {
+		return nil, errors.Wrap(err, "Failed to create SSM client for ACL definitions")
+	}
+	if c.ids, err = scopedSSMClient(ssmClient, i.IDAWS); err != nil {
+		return nil, errors.Wrap(err, "Failed to create SSM client for ACL IDs")
+	}
+	if c.tokens, err = scopedSSMClient(ssmClient, i.TokenAWS); err != nil {
+		return nil, errors.Wrap(err, "Failed to create SSM client for management token")
+	}
 	c.kmsKeyID = i.KMSKeyID
 	c.overwrite = i.Overwrite
 	c.insecure = i.Insecure
+	c.consulInput = i.Consul
 
-	consulConfig := consulapi.DefaultConfig()
 	if i.ConsulTokenParam != "" {
-		val, err := c.GetStringParameter(i.ConsulTokenParam, true)
+		val, err := c.getStringParameter(c.tokens, i.ConsulTokenParam, true)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to get management token from SSM parameter \"%s\"", i.ConsulTokenParam)
 		}
 		log.Debugf("Using Consul token from SSM parameter \"%s\"", i.ConsulTokenParam)
-		consulConfig.Token = *val
+		c.consulToken = *val
+	} else if i.Consul.TokenFile != "" {
+		data, err := ioutil.ReadFile(i.Consul.TokenFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read Consul token file \"%s\"", i.Consul.TokenFile)
+		}
+		log.Debugf("Using Consul token from file \"%s\"", i.Consul.TokenFile)
+		c.consulToken = strings.TrimSpace(string(data))
 	}
 
-	consulClient, err := consulapi.NewClient(consulConfig)
+	consulClient, err := c.newConsulClient(c.consulToken)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to create Consul client")
 	}
@@ -57,9 +114,53 @@ func NewClientSet(i *ClientSetInput) (*ClientSet, error) {
 	return &c, nil
 }
 
+// newConsulClient creates a Consul client using the given token
+func (c *ClientSet) newConsulClient(token string) (*consulapi.Client, error) {
+	consulConfig := consulapi.DefaultConfig()
+	if token != "" {
+		consulConfig.Token = token
+	}
+
+	i := c.consulInput
+	if i.Address != "" {
+		consulConfig.Address = i.Address
+	}
+	if i.Scheme != "" {
+		consulConfig.Scheme = i.Scheme
+	}
+	if i.Datacenter != "" {
+		consulConfig.Datacenter = i.Datacenter
+	}
+	if i.CAFile != "" {
+		consulConfig.TLSConfig.CAFile = i.CAFile
+	}
+	if i.CAPath != "" {
+		consulConfig.TLSConfig.CAPath = i.CAPath
+	}
+	if i.CertFile != "" {
+		consulConfig.TLSConfig.CertFile = i.CertFile
+	}
+	if i.KeyFile != "" {
+		consulConfig.TLSConfig.KeyFile = i.KeyFile
+	}
+	if i.TLSServerName != "" {
+		consulConfig.TLSConfig.Address = i.TLSServerName
+	}
+	if i.InsecureSkipVerify {
+		consulConfig.TLSConfig.InsecureSkipVerify = true
+	}
+
+	return consulapi.NewClient(consulConfig)
+}
+
 // GetStringParameter reads a SSM parameter and returns a string
 func (c *ClientSet) GetStringParameter(name string, failNotFound bool) (*string, error) {
-	resp, err := c.SSM.GetParameter(&ssm.GetParameterInput{
+	return c.getStringParameter(c.SSM, name, failNotFound)
+}
+
+// getStringParameter reads a SSM parameter using the given client
+func (c *ClientSet) getStringParameter(svc *ssm.SSM, name string, failNotFound bool) (*string, error) {
+	resp, err := svc.GetParameter(&ssm.GetParameterInput{
 		Name:           aws.String(name),
 		WithDecryption: aws.Bool(true),
 	})
@@ -77,7 +178,19 @@ func (c *ClientSet) GetStringParameter(name string, failNotFound bool) (*string,
 }
 
 // PutStringParameter writes a SSM parameter as a string
-func (c *ClientSet) PutStringParameter(name, value string) (err error) {
+func (c *ClientSet) PutStringParameter(name, value string) error {
+	return c.putStringParameter(c.SSM, name, value)
+}
+
+// putStringParameter writes a SSM parameter using the given client
+func (c *ClientSet) putStringParameter(svc *ssm.SSM, name, value string) error {
+	_, err := c.putStringParameterVersion(svc, name, value)
+	return err
+}
+
+// putStringParameterVersion writes a SSM parameter using the given client
+// and returns the version written
+func (c *ClientSet) putStringParameterVersion(svc *ssm.SSM, name, value string) (int64, error) {
 	i := ssm.PutParameterInput{
 		Name:      aws.String(name),
 		Value:     aws.String(value),
@@ -93,8 +206,11 @@ func (c *ClientSet) PutStringParameter(name, value string) (err error) {
 	}
 
 	log.Debugf("Setting %s parameter: %s", *i.Type, name)
-	_, err = c.SSM.PutParameter(&i)
-	return
+	output, err := svc.PutParameter(&i)
+	if err != nil {
+		return 0, err
+	}
+	return aws.Int64Value(output.Version), nil
 }
 
 // isLeader determines if current agent is the Consul leader
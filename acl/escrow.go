@@ -0,0 +1,173 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// EscrowInput is the input for the Escrow function
+type EscrowInput struct {
+	// Prefix is the SSM heirarchy the shares are written under, as share-1 to share-N
+	Prefix    string
+	Shares    int
+	Threshold int
+	// KMSKeyIDs has one distinct key per share
+	KMSKeyIDs []string
+}
+
+// Escrow splits a management token into shares, any threshold of which
+// recover it, and writes each share to its own SSM parameter encrypted
+// with its own KMS key. Existing shares under the prefix are replaced,
+// and shares numbered above the new share count are deleted.
+func (c *ClientSet) Escrow(token string, i *EscrowInput) error {
+	prefix := ensureTrailingSlash(i.Prefix)
+	if prefix == "" {
+		return errors.New("Prefix is required")
+	}
+	if len(i.KMSKeyIDs) != i.Shares {
+		return errors.Errorf("%d KMS key IDs are required, one per share, got %d", i.Shares, len(i.KMSKeyIDs))
+	}
+	seen := make(map[string]bool)
+	for _, key := range i.KMSKeyIDs {
+		if key == "" || seen[key] {
+			return errors.New("Each share requires a different KMS key ID")
+		}
+		seen[key] = true
+	}
+
+	shares, err := splitSecret([]byte(token), i.Shares, i.Threshold)
+	if err != nil {
+		return errors.Wrap(err, "Failed to split management token")
+	}
+	checksum := escrowChecksum(token)
+
+	for n, share := range shares {
+		param := fmt.Sprintf("%sshare-%d", prefix, n+1)
+		log.Infof("Writing escrow share %d of %d to SSM parameter \"%s\".", n+1, i.Shares, param)
+		if _, err := c.tokens.PutParameter(&ssm.PutParameterInput{
+			Name:        aws.String(param),
+			Value:       aws.String(encodeShare(i.Threshold, checksum, share)),
+			Description: aws.String(fmt.Sprintf("Consul management token escrow share %d of %d, threshold %d", n+1, i.Shares, i.Threshold)),
+			Type:        aws.String("SecureString"),
+			KeyId:       aws.String(i.KMSKeyIDs[n]),
+			Overwrite:   aws.Bool(true),
+		}); err != nil {
+			return errors.Wrapf(err, "Failed to save escrow share to SSM parameter \"%s\"", param)
+		}
+	}
+
+	// shares left from an earlier escrow with more shares would
+	// otherwise mix with the new ones
+	for n := i.Shares + 1; n <= 255; n++ {
+		param := fmt.Sprintf("%sshare-%d", prefix, n)
+		_, err := c.tokens.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(param)})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to delete stale escrow share SSM parameter \"%s\"", param)
+		}
+		log.Infof("Deleted stale escrow share SSM parameter \"%s\".", param)
+	}
+	return nil
+}
+
+// EscrowFromParameter escrows the management token stored in an SSM parameter
+func (c *ClientSet) EscrowFromParameter(consulTokenParam string, i *EscrowInput) error {
+	token, err := c.getStringParameter(c.tokens, consulTokenParam, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get management token from SSM parameter \"%s\"", consulTokenParam)
+	}
+	return c.Escrow(*token, i)
+}
+
+// RecoverEscrowInput is the input for the RecoverEscrow function
+type RecoverEscrowInput struct {
+	// Params are SSM parameters holding shares
+	Params []string
+	// Shares are share values given directly, e.g. read by another person
+	Shares []string
+}
+
+// RecoverEscrow reassembles a management token from escrow shares
+func (c *ClientSet) RecoverEscrow(i *RecoverEscrowInput) (string, error) {
+	values := append([]string{}, i.Shares...)
+	for _, param := range i.Params {
+		val, err := c.getStringParameter(c.tokens, param, true)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to get escrow share from SSM parameter \"%s\"", param)
+		}
+		values = append(values, *val)
+	}
+	return recoverToken(values)
+}
+
+// recoverToken is a helper for RecoverEscrow and combines encoded shares
+func recoverToken(values []string) (string, error) {
+	var threshold int
+	var checksum string
+	var shares [][]byte
+	for n, value := range values {
+		k, sum, share, err := decodeShare(strings.TrimSpace(value))
+		if err != nil {
+			return "", errors.Wrapf(err, "Invalid escrow share %d", n+1)
+		}
+		if n > 0 && (k != threshold || sum != checksum) {
+			return "", errors.New("Escrow shares belong to different escrows")
+		}
+		threshold, checksum = k, sum
+		shares = append(shares, share)
+	}
+	if len(shares) < threshold || len(shares) == 0 {
+		return "", errors.Errorf("%d escrow shares are required, got %d", threshold, len(shares))
+	}
+
+	secret, err := combineShares(shares)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to combine escrow shares")
+	}
+	token := string(secret)
+	if escrowChecksum(token) != checksum {
+		return "", errors.New("Recovered token doesn't match the escrow checksum")
+	}
+	return token, nil
+}
+
+// escrowChecksum identifies the token an escrow share belongs to
+// without revealing it
+func escrowChecksum(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:4])
+}
+
+// encodeShare encodes a share as THRESHOLD:CHECKSUM:BASE64
+func encodeShare(threshold int, checksum string, share []byte) string {
+	return fmt.Sprintf("%d:%s:%s", threshold, checksum, base64.StdEncoding.EncodeToString(share))
+}
+
+// decodeShare decodes a share encoded by encodeShare
+func decodeShare(value string) (int, string, []byte, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, "", nil, errors.New("Expected THRESHOLD:CHECKSUM:SHARE")
+	}
+	threshold, err := strconv.Atoi(parts[0])
+	if err != nil || threshold < 2 {
+		return 0, "", nil, errors.Errorf("Invalid threshold \"%s\"", parts[0])
+	}
+	share, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, "", nil, errors.Wrap(err, "Invalid share")
+	}
+	return threshold, parts[1], share, nil
+}
@@ -0,0 +1,166 @@
+package acl
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// createdAtTagKey is the tag on an ID parameter recording when
+// its ACL's definition was created
+const createdAtTagKey = "CreatedAt"
+
+// expiry returns when an ACL expires, or the zero time if it never does.
+// A TTL counts from when the definition parameter was first created, and
+// if both ExpiresAt and TTL are set, whichever comes first applies. The
+// creation time is kept on acl for tagIDParameter to record.
+func (c *ClientSet) expiry(acl *aclItem, aclIDPrefix string) (time.Time, error) {
+	var expires time.Time
+
+	if acl.ExpiresAt != "" {
+		t, err := parseExpiresAt(acl.ExpiresAt)
+		if err != nil {
+			return expires, errors.Wrapf(err, "Invalid ExpiresAt for ACL %s", acl.slug)
+		}
+		expires = t
+	}
+
+	if acl.TTL != "" {
+		ttl, err := parseTTL(acl.TTL)
+		if err != nil {
+			return expires, errors.Wrapf(err, "Invalid TTL for ACL %s", acl.slug)
+		}
+		created, err := c.definitionCreated(acl, aclIDPrefix)
+		if err != nil {
+			return expires, errors.Wrapf(err, "Failed to get creation time of ACL definition %s", acl.param)
+		}
+		acl.createdAt = created
+		if t := created.Add(ttl); expires.IsZero() || t.Before(expires) {
+			expires = t
+		}
+	}
+
+	return expires, nil
+}
+
+// checkExpiry marks an expired ACL for destruction, and warns
+// about ACLs expiring within the warning window
+func (c *ClientSet) checkExpiry(acl *aclItem, aclIDPrefix string, warning time.Duration) error {
+	if acl.Destroy || (acl.ExpiresAt == "" && acl.TTL == "") {
+		return nil
+	}
+	expires, err := c.expiry(acl, aclIDPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !now.Before(expires) {
+		log.Infof("ACL %s (Name: \"%s\") expired at %s.", acl.slug, acl.Name, expires.UTC().Format(time.RFC3339))
+		acl.Destroy = true
+		acl.expired = true
+	} else if expires.Sub(now) <= warning {
+		log.Warnf("ACL %s (Name: \"%s\") expires at %s.", acl.slug, acl.Name, expires.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// cleanupExpired deletes the ID parameter of an expired ACL once the ACL is gone
+func (c *ClientSet) cleanupExpired(acl *aclItem, aclIDPrefix string) {
+	if !acl.idFromParam {
+		return
+	}
+	if currentACL, _, err := c.Consul.ACL().Info(acl.ID, nil); err != nil || currentACL != nil {
+		log.Warnf("Keeping ID parameter of expired ACL %s (Name: \"%s\"), the ACL still exists.", acl.slug, acl.Name)
+		return
+	}
+	idParam := aclIDPrefix + acl.slug
+	log.Infof("Deleting ID parameter of expired ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+	if _, err := c.ids.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(idParam)}); err != nil {
+		log.Errorf("Failed to delete SSM parameter \"%s\": %s", idParam, err.Error())
+	}
+	// tags went with the parameter
+	delete(c.metadataTagged, idParam)
+	delete(c.definitionsCreated, idParam)
+}
+
+// definitionCreated returns when an ACL's definition parameter was created, from
+// the CreatedAt tag of its ID parameter. Without the tag, the oldest version in
+// the definition's history is used; tagIDParameter then records it, as SSM only
+// keeps the last 100 versions and the oldest would move forward with each edit.
+// Definitions are only read, the creation time is never tagged on them.
+func (c *ClientSet) definitionCreated(acl *aclItem, aclIDPrefix string) (time.Time, error) {
+	if aclIDPrefix == "" {
+		return c.parameterCreated(acl.param)
+	}
+	idParam := aclIDPrefix + acl.slug
+	if created, ok := c.definitionsCreated[idParam]; ok {
+		return created, nil
+	}
+
+	output, err := c.ids.ListTagsForResource(&ssm.ListTagsForResourceInput{
+		ResourceType: aws.String(tagResourceType),
+		ResourceId:   aws.String(idParam),
+	})
+	if err != nil {
+		// the ID parameter is only written once the ACL is created
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvalidResourceId {
+			return c.parameterCreated(acl.param)
+		}
+		return time.Time{}, errors.Wrapf(err, "Failed to list tags of SSM parameter \"%s\"", idParam)
+	}
+	for _, tag := range output.TagList {
+		if aws.StringValue(tag.Key) != createdAtTagKey {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, aws.StringValue(tag.Value))
+		if err != nil {
+			return created, errors.Wrapf(err, "Invalid %s tag on SSM parameter \"%s\"", createdAtTagKey, idParam)
+		}
+		if c.definitionsCreated == nil {
+			c.definitionsCreated = make(map[string]time.Time)
+		}
+		c.definitionsCreated[idParam] = created
+		return created, nil
+	}
+	return c.parameterCreated(acl.param)
+}
+
+// parameterCreated returns the time of the oldest version of a definition parameter
+// in its history, which SSM limits to the last 100 versions
+func (c *ClientSet) parameterCreated(name string) (time.Time, error) {
+	var created time.Time
+	err := c.definitions.GetParameterHistoryPages(&ssm.GetParameterHistoryInput{
+		Name: aws.String(name),
+	}, func(output *ssm.GetParameterHistoryOutput, lastPage bool) bool {
+		for _, h := range output.Parameters {
+			t := aws.TimeValue(h.LastModifiedDate)
+			if created.IsZero() || t.Before(created) {
+				created = t
+			}
+		}
+		return true
+	})
+	return created, err
+}
+
+// parseExpiresAt parses an RFC 3339 expiry timestamp
+func parseExpiresAt(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseTTL parses a positive TTL duration, e.g. "720h"
+func parseTTL(s string) (time.Duration, error) {
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if ttl <= 0 {
+		return 0, errors.Errorf("TTL must be positive, got \"%s\"", s)
+	}
+	return ttl, nil
+}
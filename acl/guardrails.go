@@ -0,0 +1,117 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Guardrails are policies ACL definitions must meet before sync applies them
+type Guardrails struct {
+	// Audit reports violations without blocking the sync
+	Audit bool `json:"audit" mapstructure:"audit"`
+	// ForbidManagement forbids management ACLs except for AllowManagement slugs
+	ForbidManagement bool     `json:"forbid-management" mapstructure:"forbid-management"`
+	AllowManagement  []string `json:"allow-management" mapstructure:"allow-management"`
+	// MaxManagement caps the number of management ACLs, zero means no cap
+	MaxManagement int             `json:"max-management" mapstructure:"max-management"`
+	RequireOwner  bool            `json:"require-owner" mapstructure:"require-owner"`
+	Forbid        []ForbiddenRule `json:"forbid" mapstructure:"forbid"`
+}
+
+// ForbiddenRule matches ACL rules that definitions may not grant. An unset
+// Segment matches any segment, and an unset Policy matches any policy.
+type ForbiddenRule struct {
+	Resource string  `json:"resource" mapstructure:"resource"`
+	Segment  *string `json:"segment" mapstructure:"segment"`
+	Policy   string  `json:"policy" mapstructure:"policy"`
+}
+
+// matches returns whether a rule is forbidden
+func (f *ForbiddenRule) matches(rule aclRule) bool {
+	if f.Resource != rule.Resource {
+		return false
+	}
+	if f.Segment != nil && *f.Segment != rule.Segment {
+		return false
+	}
+	return f.Policy == "" || f.Policy == rule.Policy
+}
+
+// check returns the guardrail violations of a set of ACLs. Unless rendered
+// is set, templated rules are skipped as they are only known at sync time.
+func (g *Guardrails) check(acls []*aclItem, rendered bool) []Finding {
+	var findings []Finding
+	add := func(acl *aclItem, message string) {
+		findings = append(findings, Finding{
+			Severity:  SeverityError,
+			Parameter: acl.param,
+			Slug:      acl.slug,
+			Check:     "guardrail",
+			Message:   message,
+		})
+	}
+
+	var management []*aclItem
+	for _, acl := range acls {
+		// destroying an ACL never widens access
+		if acl.Destroy {
+			continue
+		}
+
+		if acl.Type == "management" {
+			management = append(management, acl)
+			if g.ForbidManagement && !contains(g.AllowManagement, acl.slug) {
+				add(acl, "Management ACLs are not allowed for this slug")
+			}
+		}
+
+		if g.RequireOwner && acl.Owner == "" {
+			add(acl, "Owner is required")
+		}
+
+		if !rendered && strings.Contains(acl.Rules, "{{") {
+			continue
+		}
+		// rules that don't parse can't be shown to be allowed
+		rules, err := parseRules(acl.Rules)
+		if err != nil {
+			add(acl, "Rules could not be checked: "+errors.Cause(err).Error())
+			continue
+		}
+		for _, rule := range rules {
+			for _, f := range g.Forbid {
+				if f.matches(rule) {
+					add(acl, fmt.Sprintf("Rule %s is forbidden", rule))
+				}
+			}
+		}
+	}
+
+	if g.MaxManagement > 0 && len(management) > g.MaxManagement {
+		for _, acl := range management {
+			add(acl, fmt.Sprintf("%d management ACLs exceed the limit of %d", len(management), g.MaxManagement))
+		}
+	}
+
+	return findings
+}
+
+// enforce logs the guardrail violations of a set of rendered ACLs,
+// returning an error unless in audit mode
+func (g *Guardrails) enforce(acls []*aclItem) error {
+	violations := g.check(acls, true)
+	for _, v := range violations {
+		if g.Audit {
+			log.Warnf("Guardrail violation (audit) for ACL %s: %s", v.Slug, v.Message)
+		} else {
+			log.Errorf("Guardrail violation for ACL %s: %s", v.Slug, v.Message)
+		}
+	}
+	if len(violations) > 0 && !g.Audit {
+		return errors.Errorf("%d guardrail violation(s), no ACLs were changed", len(violations))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package acl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// idGenerationsDir is the sub-prefix of the ID prefix holding the generation
+// of each slug, it is kept apart from the ID parameters so losing an ID
+// parameter can't bring back the ID of a rotated or revoked ACL
+const idGenerationsDir = "_generations"
+
+// deterministicID derives the ID of an ACL from a secret key, the ACL's slug
+// and its generation, so the same definitions always produce the same token
+// IDs. The ID is HMAC-SHA256 based with the version 5 bits set, it isn't an
+// RFC 4122 name-based (SHA-1) UUID. Generation 0 hashes the slug alone.
+func deterministicID(key, slug string, generation int) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(slug))
+	if generation > 0 {
+		mac.Write([]byte("\x00" + strconv.Itoa(generation)))
+	}
+	b := mac.Sum(nil)[:16]
+
+	// set version (5) and RFC 4122 variant bits
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return formatUUID(b)
+}
+
+// idGeneration returns how often the ACL of a slug was rotated or revoked
+func (c *ClientSet) idGeneration(aclIDPrefix, slug string) (int, error) {
+	param := aclIDPrefix + idGenerationsDir + "/" + slug
+	val, err := c.getStringParameter(c.ids, param, false)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to get ID generation from SSM parameter \"%s\"", param)
+	}
+	if *val == "" {
+		return 0, nil
+	}
+	generation, err := strconv.Atoi(*val)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid ID generation in SSM parameter \"%s\"", param)
+	}
+	return generation, nil
+}
+
+// bumpIDGeneration is called whenever the ACL of a slug gets a new ID,
+// so a deterministic ID derived later differs from every previous one
+func (c *ClientSet) bumpIDGeneration(aclIDPrefix, slug string) error {
+	generation, err := c.idGeneration(aclIDPrefix, slug)
+	if err != nil {
+		return err
+	}
+	param := aclIDPrefix + idGenerationsDir + "/" + slug
+	if err := c.putStringParameter(c.ids, param, strconv.Itoa(generation+1)); err != nil {
+		return errors.Wrapf(err, "Failed to save ID generation to SSM parameter \"%s\"", param)
+	}
+	return nil
+}
+
+// idFingerprint identifies a token ID in output without revealing it,
+// as the ID of a legacy ACL is its secret
+func idFingerprint(id string) string {
+	if id == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:4])
+}
+
+// randomID generates a random (version 4) UUID
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	// set version (4, random) and RFC 4122 variant bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return formatUUID(b), nil
+}
+
+// formatUUID formats 16 bytes in canonical UUID form
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
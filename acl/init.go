@@ -0,0 +1,194 @@
+package acl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	initPhaseBootstrap = "bootstrap"
+	initPhaseSync      = "sync"
+	initPhaseAgent     = "agent"
+)
+
+// InitInput is the input for the Init function
+type InitInput struct {
+	ConsulTokenParam string
+	Sync             *SyncInput
+	// AgentTokens maps agent token types to SSM parameters holding token IDs
+	AgentTokens  map[string]string
+	Wait         bool
+	WaitTimeout  time.Duration
+	WaitInterval time.Duration
+	// StateFile optionally records completed phases so a rerun resumes after a failure
+	StateFile string
+}
+
+// initState is the content of the Init state file
+type initState struct {
+	Completed []string
+}
+
+// Init initializes a cluster's ACLs in a single run: it waits for Consul,
+// bootstraps ACLs, syncs ACL definitions and installs agent tokens
+func (c *ClientSet) Init(i *InitInput) error {
+	if i.ConsulTokenParam == "" {
+		return errors.New("ConsulTokenParam cannot be empty")
+	}
+
+	state, err := readInitState(i.StateFile)
+	if err != nil {
+		return err
+	}
+
+	if i.Wait {
+		log.Info("Phase wait: waiting for Consul")
+		if err := c.WaitForConsul(i.WaitTimeout, i.WaitInterval); err != nil {
+			return err
+		}
+	}
+
+	phases := []struct {
+		name string
+		fn   func(*InitInput) error
+	}{
+		{initPhaseBootstrap, c.initBootstrap},
+		{initPhaseSync, c.initSync},
+		{initPhaseAgent, c.initAgent},
+	}
+
+	for _, phase := range phases {
+		if state.completed(phase.name) {
+			log.Infof("Phase %s: skipped, completed by a previous run", phase.name)
+			if phase.name == initPhaseBootstrap {
+				if err := c.useTokenParam(i.ConsulTokenParam); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		log.Infof("Phase %s: starting", phase.name)
+		if err := phase.fn(i); err != nil {
+			return errors.Wrapf(err, "Phase %s failed", phase.name)
+		}
+		log.Infof("Phase %s: complete", phase.name)
+
+		state.Completed = append(state.Completed, phase.name)
+		if err := writeInitState(i.StateFile, state); err != nil {
+			return err
+		}
+	}
+
+	// all phases done, the next run starts from the beginning
+	if i.StateFile != "" {
+		if err := os.Remove(i.StateFile); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Failed to remove state file \"%s\"", i.StateFile)
+		}
+	}
+
+	return nil
+}
+
+// initBootstrap is the Init bootstrap phase
+func (c *ClientSet) initBootstrap(i *InitInput) error {
+	_, err := c.Bootstrap(i.ConsulTokenParam)
+	if err != nil && errors.Cause(err) != ErrAlreadyBootstrapped {
+		return err
+	}
+	if err != nil {
+		log.Info(err.Error())
+	}
+	return c.useTokenParam(i.ConsulTokenParam)
+}
+
+// initSync is the Init sync phase
+func (c *ClientSet) initSync(i *InitInput) error {
+	if i.Sync == nil || i.Sync.ACLDefinitionPrefix == "" {
+		log.Info("No ACL definition prefix given, nothing to sync")
+		return nil
+	}
+	return c.Sync(i.Sync)
+}
+
+// initAgent is the Init agent phase
+func (c *ClientSet) initAgent(i *InitInput) error {
+	tokenTypes := make([]string, 0, len(i.AgentTokens))
+	for tokenType := range i.AgentTokens {
+		tokenTypes = append(tokenTypes, tokenType)
+	}
+	sort.Strings(tokenTypes)
+
+	for _, tokenType := range tokenTypes {
+		param := i.AgentTokens[tokenType]
+		if err := c.UpdateAgentTokenFromParameter(tokenType, param); err != nil {
+			return errors.Wrapf(err, "Failed to set agent %s", tokenType)
+		}
+		log.Infof("Set agent %s from SSM parameter \"%s\"", tokenType, param)
+	}
+	return nil
+}
+
+// useTokenParam switches the Consul client to the token stored in an SSM parameter
+func (c *ClientSet) useTokenParam(consulTokenParam string) error {
+	val, err := c.getStringParameter(c.tokens, consulTokenParam, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get management token from SSM parameter \"%s\"", consulTokenParam)
+	}
+	client, err := c.newConsulClient(*val)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create Consul client")
+	}
+	c.Consul = client
+	c.consulToken = *val
+	return nil
+}
+
+// completed returns whether the given phase has completed
+func (s *initState) completed(phase string) bool {
+	for _, p := range s.Completed {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// readInitState reads the Init state file, if any
+func readInitState(path string) (*initState, error) {
+	var state initState
+	if path == "" {
+		return &state, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read state file \"%s\"", path)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse state file \"%s\"", path)
+	}
+	return &state, nil
+}
+
+// writeInitState writes the Init state file, if any
+func writeInitState(path string, state *initState) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return errors.Wrapf(err, "Failed to write state file \"%s\"", path)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package acl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ConfigStyleLegacy renders the token as acl_master_token
+	ConfigStyleLegacy = "legacy"
+
+	// ConfigStyleTokens renders the token as acl.tokens.initial_management
+	ConfigStyleTokens = "tokens"
+)
+
+// InitTokenInput is the input for the InitToken function
+type InitTokenInput struct {
+	ConsulTokenParam string
+	ConfigFile       string
+	ConfigStyle      string
+}
+
+// InitToken generates a management token, saves it to an SSM parameter, and
+// renders it into a Consul server configuration file so ACLs are bootstrapped
+// from configuration when Consul starts. A token already stored in the
+// parameter is reused unless overwrite is enabled.
+func (c *ClientSet) InitToken(i *InitTokenInput) (string, error) {
+	if i.ConsulTokenParam == "" {
+		return "", errors.New("ConsulTokenParam cannot be empty")
+	}
+	if i.ConfigFile == "" {
+		return "", errors.New("ConfigFile cannot be empty")
+	}
+
+	// validate the style before generating anything
+	if _, err := tokenConfig(i.ConfigStyle, ""); err != nil {
+		return "", err
+	}
+
+	val, err := c.getStringParameter(c.tokens, i.ConsulTokenParam, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get SSM parameter \"%s\"", i.ConsulTokenParam)
+	}
+
+	id := *val
+	if id != "" && !c.overwrite {
+		log.Infof("Using existing management token from SSM parameter \"%s\"", i.ConsulTokenParam)
+	} else {
+		if id, err = randomID(); err != nil {
+			return "", errors.Wrap(err, "Failed to generate management token")
+		}
+		if err := c.putStringParameter(c.tokens, i.ConsulTokenParam, id); err != nil {
+			return "", errors.Wrapf(err, "Failed to save management token to SSM parameter \"%s\"", i.ConsulTokenParam)
+		}
+		log.Infof("Saved new management token to SSM parameter \"%s\"", i.ConsulTokenParam)
+	}
+
+	config, _ := tokenConfig(i.ConfigStyle, id)
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return id, errors.Wrap(err, "Failed to render Consul configuration")
+	}
+	if err := ioutil.WriteFile(i.ConfigFile, append(data, '\n'), 0600); err != nil {
+		return id, errors.Wrapf(err, "Failed to write Consul configuration to \"%s\"", i.ConfigFile)
+	}
+	log.Infof("Wrote management token configuration to \"%s\"", i.ConfigFile)
+
+	return id, nil
+}
+
+// tokenConfig is a helper for InitToken and builds the
+// Consul configuration holding the management token
+func tokenConfig(style, id string) (map[string]interface{}, error) {
+	switch style {
+	case ConfigStyleLegacy, "":
+		return map[string]interface{}{
+			"acl_master_token": id,
+		}, nil
+	case ConfigStyleTokens:
+		return map[string]interface{}{
+			"acl": map[string]interface{}{
+				"tokens": map[string]interface{}{
+					"initial_management": id,
+				},
+			},
+		}, nil
+	}
+	return nil, errors.Errorf("Unknown configuration style \"%s\"", style)
+}
@@ -0,0 +1,178 @@
+package acl
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// fragmentsDir is the sub-prefix holding reusable rule fragments
+	fragmentsDir = "_fragments"
+
+	// defaultsName is the name of the document holding defaults
+	// for the definitions in the same sub-prefix and below
+	defaultsName = "_defaults"
+)
+
+// aclDefaults holds the defaults applied to definitions in a sub-prefix
+type aclDefaults struct {
+	Type    string
+	Rules   string
+	Include []string
+}
+
+// definitionLibrary holds the ACL definitions under a prefix along
+// with the fragments and defaults they can be composed from
+type definitionLibrary struct {
+	prefix      string
+	definitions []*ssm.Parameter
+	fragments   map[string]string
+	defaults    map[string]*aclDefaults
+}
+
+// newDefinitionLibrary sorts the parameters under a prefix into
+// definitions, fragments and defaults
+func newDefinitionLibrary(prefix string, params []*ssm.Parameter) (*definitionLibrary, error) {
+	lib := &definitionLibrary{
+		prefix:    prefix,
+		fragments: make(map[string]string),
+		defaults:  make(map[string]*aclDefaults),
+	}
+
+	for _, param := range params {
+		rel := strings.TrimPrefix(*param.Name, prefix)
+
+		if strings.HasPrefix(rel, fragmentsDir+"/") {
+			lib.fragments[strings.TrimPrefix(rel, fragmentsDir+"/")] = *param.Value
+			continue
+		}
+
+		if path.Base(rel) == defaultsName {
+			var d aclDefaults
+			if err := json.Unmarshal([]byte(*param.Value), &d); err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse defaults from %s", *param.Name)
+			}
+			lib.defaults[path.Dir(rel)] = &d
+			continue
+		}
+
+		lib.definitions = append(lib.definitions, param)
+	}
+
+	return lib, nil
+}
+
+// parseDefinition converts an SSM parameter to an aclItem,
+// composing it from any defaults and fragments
+func (lib *definitionLibrary) parseDefinition(param *ssm.Parameter) (*aclItem, error) {
+	acl := aclItem{param: *param.Name}
+	parts := strings.Split(strings.TrimPrefix(*param.Name, lib.prefix), "/")
+	acl.slug = parts[len(parts)-1]
+	log.Debugf("Got SSM parameter name: %s, value: %s, slug: %s", *param.Name, *param.Value, acl.slug)
+
+	if err := json.Unmarshal([]byte(*param.Value), &acl); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse parameter %s from %s as acl", acl.slug, *param.Name)
+	}
+
+	// apply sub-prefix defaults and included fragments
+	if err := lib.compose(&acl, *param.Name); err != nil {
+		return nil, errors.Wrapf(err, "Failed to compose ACL %s from %s", acl.slug, *param.Name)
+	}
+
+	// Type should default to client
+	if acl.Type == "" {
+		acl.Type = "client"
+	}
+
+	return &acl, nil
+}
+
+// compose applies the defaults of a definition's sub-prefixes and its
+// included fragments, building the final Type and Rules
+func (lib *definitionLibrary) compose(acl *aclItem, name string) error {
+	var parts []string
+	composed := false
+
+	// defaults apply from the outermost sub-prefix inwards,
+	// so the nearest defaults win for Type
+	defaultType := ""
+	for _, dir := range ancestorDirs(path.Dir(strings.TrimPrefix(name, lib.prefix))) {
+		d, ok := lib.defaults[dir]
+		if !ok {
+			continue
+		}
+		composed = true
+		if d.Type != "" {
+			defaultType = d.Type
+		}
+		fragments, err := lib.fragmentRules(d.Include)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fragments...)
+		parts = append(parts, d.Rules)
+	}
+
+	if len(acl.Include) > 0 {
+		composed = true
+		fragments, err := lib.fragmentRules(acl.Include)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fragments...)
+	}
+
+	if acl.Type == "" {
+		acl.Type = defaultType
+	}
+	if composed {
+		acl.Rules = joinRules(append(parts, acl.Rules))
+	}
+	return nil
+}
+
+// fragmentRules returns the rules of the named fragments
+func (lib *definitionLibrary) fragmentRules(names []string) ([]string, error) {
+	var rules []string
+	for _, name := range names {
+		fragment, ok := lib.fragments[name]
+		if !ok {
+			return nil, errors.Errorf("Fragment \"%s\" not found under %s%s/", name, lib.prefix, fragmentsDir)
+		}
+		rules = append(rules, fragment)
+	}
+	return rules, nil
+}
+
+// ancestorDirs returns a relative directory and all of its parents,
+// outermost first, with "." being the prefix itself
+func ancestorDirs(dir string) []string {
+	dirs := []string{"."}
+	if dir == "." || dir == "" {
+		return dirs
+	}
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		dirs = append(dirs, strings.Join(parts[:i+1], "/"))
+	}
+	return dirs
+}
+
+// joinRules joins rule blocks, skipping empty ones
+func joinRules(parts []string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		p = strings.TrimRight(p, "\n")
+		if p == "" {
+			continue
+		}
+		b.WriteString(p)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
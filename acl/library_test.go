@@ -0,0 +1,186 @@
+package acl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// testPrefix is the definition prefix used by tests
+const testPrefix = "/consul/acl/"
+
+// testParams returns parameters under testPrefix, sorted by name
+// as SSM returns them
+func testParams(values map[string]string) []*ssm.Parameter {
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []*ssm.Parameter
+	for _, name := range names {
+		params = append(params, &ssm.Parameter{
+			Name:  aws.String(testPrefix + name),
+			Value: aws.String(values[name]),
+		})
+	}
+	return params
+}
+
+func TestNewDefinitionLibrary(t *testing.T) {
+	lib, err := newDefinitionLibrary(testPrefix, testParams(map[string]string{
+		"_fragments/read-kv":    `key "" { policy = "read" }`,
+		"_defaults":             `{"Type": "client"}`,
+		"team-a/_defaults":      `{"Rules": "service \"a\" { policy = \"write\" }"}`,
+		"team-a/app":            `{"Name": "App"}`,
+		"web":                   `{"Name": "Web"}`,
+		"_fragments/nested/ops": `operator = "read"`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var definitions []string
+	for _, param := range lib.definitions {
+		definitions = append(definitions, *param.Name)
+	}
+	if got, want := strings.Join(definitions, ","), testPrefix+"team-a/app,"+testPrefix+"web"; got != want {
+		t.Errorf("definitions = %s, want %s", got, want)
+	}
+	if _, ok := lib.fragments["nested/ops"]; !ok || len(lib.fragments) != 2 {
+		t.Errorf("fragments = %v", lib.fragments)
+	}
+	if _, ok := lib.defaults["team-a"]; !ok || len(lib.defaults) != 2 {
+		t.Errorf("defaults = %v", lib.defaults)
+	}
+
+	if _, err := newDefinitionLibrary(testPrefix, testParams(map[string]string{
+		"_defaults": `{"Type": `,
+	})); err == nil {
+		t.Error("expected error for invalid defaults")
+	}
+}
+
+func TestParseDefinitionComposition(t *testing.T) {
+	params := map[string]string{
+		"_fragments/read-kv":   `key "" { policy = "read" }`,
+		"_fragments/ops":       `operator = "read"`,
+		"_defaults":            `{"Rules": "node \"\" { policy = \"read\" }"}`,
+		"team-a/_defaults":     `{"Type": "management", "Include": ["ops"], "Rules": "service \"a\" { policy = \"write\" }"}`,
+		"team-a/svc/_defaults": `{"Type": "client"}`,
+	}
+	cases := []struct {
+		name       string
+		definition string
+		wantType   string
+		wantRules  string
+		wantErr    string
+	}{
+		{
+			name:       "top",
+			definition: `{"Name": "Top", "Rules": "event \"\" { policy = \"read\" }"}`,
+			wantType:   "client",
+			wantRules:  "node \"\" { policy = \"read\" }\nevent \"\" { policy = \"read\" }\n",
+		},
+		{
+			name:       "team-a/app",
+			definition: `{"Name": "App", "Include": ["read-kv"]}`,
+			wantType:   "management",
+			wantRules: "node \"\" { policy = \"read\" }\noperator = \"read\"\nservice \"a\" { policy = \"write\" }\n" +
+				"key \"\" { policy = \"read\" }\n",
+		},
+		{
+			name:       "team-a/svc/api",
+			definition: `{"Name": "API"}`,
+			wantType:   "client",
+			wantRules:  "node \"\" { policy = \"read\" }\noperator = \"read\"\nservice \"a\" { policy = \"write\" }\n",
+		},
+		{
+			name:       "team-a/own-type",
+			definition: `{"Name": "Own", "Type": "client"}`,
+			wantType:   "client",
+			wantRules:  "node \"\" { policy = \"read\" }\noperator = \"read\"\nservice \"a\" { policy = \"write\" }\n",
+		},
+		{
+			name:       "missing",
+			definition: `{"Name": "Missing", "Include": ["nope"]}`,
+			wantErr:    "Fragment \"nope\" not found",
+		},
+		{
+			name:       "invalid",
+			definition: `{"Name": `,
+			wantErr:    "Failed to parse parameter invalid",
+		},
+	}
+
+	for _, tc := range cases {
+		values := map[string]string{tc.name: tc.definition}
+		for name, value := range params {
+			values[name] = value
+		}
+		lib, err := newDefinitionLibrary(testPrefix, testParams(values))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lib.definitions) != 1 {
+			t.Fatalf("%s: got %d definitions", tc.name, len(lib.definitions))
+		}
+
+		acl, err := lib.parseDefinition(lib.definitions[0])
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+		if want := tc.name[strings.LastIndex(tc.name, "/")+1:]; acl.slug != want {
+			t.Errorf("%s: slug = %q, want %q", tc.name, acl.slug, want)
+		}
+		if acl.Type != tc.wantType {
+			t.Errorf("%s: Type = %q, want %q", tc.name, acl.Type, tc.wantType)
+		}
+		if acl.Rules != tc.wantRules {
+			t.Errorf("%s: Rules = %q, want %q", tc.name, acl.Rules, tc.wantRules)
+		}
+	}
+}
+
+func TestAncestorDirs(t *testing.T) {
+	cases := []struct {
+		dir  string
+		want string
+	}{
+		{".", "."},
+		{"", "."},
+		{"a", ".,a"},
+		{"a/b/c", ".,a,a/b,a/b/c"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(ancestorDirs(tc.dir), ","); got != tc.want {
+			t.Errorf("ancestorDirs(%q) = %s, want %s", tc.dir, got, tc.want)
+		}
+	}
+}
+
+func TestJoinRules(t *testing.T) {
+	cases := []struct {
+		parts []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"", "\n"}, ""},
+		{[]string{"a\n\n", "", "b"}, "a\nb\n"},
+	}
+	for _, tc := range cases {
+		if got := joinRules(tc.parts); got != tc.want {
+			t.Errorf("joinRules(%q) = %q, want %q", tc.parts, got, tc.want)
+		}
+	}
+}
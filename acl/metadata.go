@@ -0,0 +1,142 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// tagResourceType is the SSM resource type of parameters
+	tagResourceType = "Parameter"
+
+	// maxTagValueLength is the longest value SSM allows for a tag
+	maxTagValueLength = 256
+
+	// metadataRecheckInterval is how long tags known to match are trusted
+	// before they are listed again, e.g. in case they were changed by hand
+	metadataRecheckInterval = time.Hour
+)
+
+// taggedMetadata records the metadata tagged on an ID parameter
+type taggedMetadata struct {
+	hash      string
+	checkedAt time.Time
+}
+
+// invalidTagValueRegexp matches the characters SSM doesn't allow in tag values
+var invalidTagValueRegexp = regexp.MustCompile(`[^\p{L}\p{Z}\p{N}_.:/=+\-@]`)
+
+// metadataTagKeys are the tags managed on ID parameters, in the order they are written
+var metadataTagKeys = []string{"Owner", "Team", "Ticket", "Description", "ExpiresAt", createdAtTagKey}
+
+// metadataTags returns the SSM tags for an ACL's metadata
+func (acl *aclItem) metadataTags() map[string]string {
+	var createdAt string
+	if !acl.createdAt.IsZero() {
+		createdAt = acl.createdAt.UTC().Format(time.RFC3339)
+	}
+	values := []string{acl.Owner, acl.Team, acl.Ticket, acl.Description, acl.ExpiresAt, createdAt}
+	tags := make(map[string]string)
+	for n, key := range metadataTagKeys {
+		value := values[n]
+		if value == "" {
+			continue
+		}
+		tags[key] = tagValue(value)
+	}
+	return tags
+}
+
+// tagValue replaces the characters SSM doesn't allow in tag
+// values and cuts the value to the allowed length
+func tagValue(value string) string {
+	value = invalidTagValueRegexp.ReplaceAllString(value, " ")
+	if r := []rune(value); len(r) > maxTagValueLength {
+		value = string(r[:maxTagValueLength])
+	}
+	return value
+}
+
+// metadataHash identifies a set of metadata tags
+func metadataHash(tags map[string]string) string {
+	h := sha256.New()
+	for _, key := range metadataTagKeys {
+		if value, ok := tags[key]; ok {
+			fmt.Fprintf(h, "%s=%s\x00", key, value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// tagIDParameter keeps the tags of an ACL's ID parameter in line with its metadata,
+// tags other than the metadata tags are left alone. Tags are only listed if the
+// metadata changed since this client last tagged the parameter, or the last
+// check is older than metadataRecheckInterval.
+func (c *ClientSet) tagIDParameter(acl *aclItem, idParam string) error {
+	want := acl.metadataTags()
+	hash := metadataHash(want)
+	if tagged, ok := c.metadataTagged[idParam]; ok && tagged.hash == hash &&
+		time.Since(tagged.checkedAt) < metadataRecheckInterval {
+		log.Debugf("Skipping tags of ACL %s (Name: \"%s\") - metadata unchanged.", acl.slug, acl.Name)
+		return nil
+	}
+
+	output, err := c.ids.ListTagsForResource(&ssm.ListTagsForResourceInput{
+		ResourceType: aws.String(tagResourceType),
+		ResourceId:   aws.String(idParam),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to list tags of SSM parameter \"%s\"", idParam)
+	}
+	current := make(map[string]string)
+	for _, tag := range output.TagList {
+		current[*tag.Key] = *tag.Value
+	}
+
+	var add []*ssm.Tag
+	var remove []*string
+	for _, key := range metadataTagKeys {
+		value, wanted := want[key]
+		existing, exists := current[key]
+		if wanted && (!exists || existing != value) {
+			add = append(add, &ssm.Tag{Key: aws.String(key), Value: aws.String(value)})
+		} else if !wanted && exists {
+			remove = append(remove, aws.String(key))
+		}
+	}
+
+	if len(add) > 0 {
+		log.Infof("Tagging ID parameter of ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+		if _, err := c.ids.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceType: aws.String(tagResourceType),
+			ResourceId:   aws.String(idParam),
+			Tags:         add,
+		}); err != nil {
+			return errors.Wrapf(err, "Failed to tag SSM parameter \"%s\"", idParam)
+		}
+	}
+	if len(remove) > 0 {
+		log.Infof("Removing tags from ID parameter of ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+		if _, err := c.ids.RemoveTagsFromResource(&ssm.RemoveTagsFromResourceInput{
+			ResourceType: aws.String(tagResourceType),
+			ResourceId:   aws.String(idParam),
+			TagKeys:      remove,
+		}); err != nil {
+			return errors.Wrapf(err, "Failed to remove tags from SSM parameter \"%s\"", idParam)
+		}
+	}
+
+	if c.metadataTagged == nil {
+		c.metadataTagged = make(map[string]taggedMetadata)
+	}
+	c.metadataTagged[idParam] = taggedMetadata{hash: hash, checkedAt: time.Now()}
+	return nil
+}
@@ -0,0 +1,190 @@
+package acl
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReportInput is the input for the Report function
+type ReportInput struct {
+	ACLDefinitionPrefix string
+	ACLIDPrefix         string
+	PageSize            int64
+	// SkipConsul leaves out whether ACLs exist in Consul
+	SkipConsul bool
+}
+
+// Report is an inventory of ACL definitions
+type Report struct {
+	Generated time.Time     `json:"generated"`
+	Prefix    string        `json:"prefix"`
+	Entries   []ReportEntry `json:"entries"`
+}
+
+// ReportEntry describes a single ACL definition
+type ReportEntry struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Parameter string `json:"parameter"`
+	Owner     string `json:"owner,omitempty"`
+	Team      string `json:"team,omitempty"`
+	Ticket    string `json:"ticket,omitempty"`
+	Destroy   bool   `json:"destroy"`
+	// Rules summarizes the rules per resource type
+	Rules map[string][]string `json:"rules"`
+	// Expires is when the ACL expires, nil if it never does
+	Expires *time.Time `json:"expires,omitempty"`
+	// IDSource is "definition", "ssm" or empty if no ID is known
+	IDSource string `json:"id_source"`
+	// InConsul is nil when Consul wasn't checked
+	InConsul         *bool     `json:"in_consul,omitempty"`
+	LastModified     time.Time `json:"last_modified"`
+	LastModifiedUser string    `json:"last_modified_user,omitempty"`
+	Version          int64     `json:"version"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// Report builds an inventory of the ACL definitions under a prefix
+func (c *ClientSet) Report(i *ReportInput) (*Report, error) {
+	prefix := ensureTrailingSlash(i.ACLDefinitionPrefix)
+	aclIDPrefix := ensureTrailingSlash(i.ACLIDPrefix)
+	if prefix == "" {
+		return nil, errors.New("ACLDefinitionPrefix is required")
+	}
+
+	params, err := c.getDefinitionParameters(prefix, i.PageSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get ACL definition parameters from prefix \"%s\"", prefix)
+	}
+	lib, err := newDefinitionLibrary(prefix, params)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := c.describeParameters(prefix, i.PageSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to describe ACL definition parameters under prefix \"%s\"", prefix)
+	}
+
+	report := &Report{Generated: time.Now().UTC(), Prefix: prefix}
+	for _, param := range lib.definitions {
+		entry := ReportEntry{Parameter: *param.Name, Slug: path.Base(*param.Name)}
+		if m, ok := metadata[*param.Name]; ok {
+			entry.LastModified = aws.TimeValue(m.LastModifiedDate)
+			entry.LastModifiedUser = aws.StringValue(m.LastModifiedUser)
+			entry.Version = aws.Int64Value(m.Version)
+		}
+
+		acl, err := lib.parseDefinition(param)
+		if err != nil {
+			entry.Error = errors.Cause(err).Error()
+			report.Entries = append(report.Entries, entry)
+			continue
+		}
+		entry.Name = acl.Name
+		entry.Type = acl.Type
+		entry.Owner = acl.Owner
+		entry.Team = acl.Team
+		entry.Ticket = acl.Ticket
+		entry.Destroy = acl.Destroy
+		entry.Rules, err = summarizeRules(acl.Rules)
+		if err != nil {
+			entry.Error = err.Error()
+		}
+
+		if acl.ExpiresAt != "" || acl.TTL != "" {
+			if expires, err := c.expiry(acl, aclIDPrefix); err != nil {
+				entry.Error = errors.Cause(err).Error()
+			} else {
+				entry.Expires = &expires
+			}
+		}
+
+		id := acl.ID
+		if id != "" {
+			entry.IDSource = "definition"
+		} else if aclIDPrefix != "" {
+			val, err := c.getStringParameter(c.ids, aclIDPrefix+acl.slug, false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to get ACL ID from SSM parameter \"%s\"", aclIDPrefix+acl.slug)
+			}
+			if id = *val; id != "" {
+				entry.IDSource = "ssm"
+			}
+		}
+
+		if !i.SkipConsul {
+			exists := false
+			if id != "" {
+				currentACL, _, err := c.Consul.ACL().Info(id, nil)
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed to get info for ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+				}
+				exists = currentACL != nil
+			}
+			entry.InConsul = &exists
+		}
+
+		report.Entries = append(report.Entries, entry)
+	}
+
+	sort.Slice(report.Entries, func(a, b int) bool {
+		return report.Entries[a].Parameter < report.Entries[b].Parameter
+	})
+	log.Debugf("Report contains %d ACL definition(s)", len(report.Entries))
+	return report, nil
+}
+
+// describeParameters returns the metadata of the parameters under a prefix by name
+func (c *ClientSet) describeParameters(prefix string, pageSize int64) (map[string]*ssm.ParameterMetadata, error) {
+	metadata := make(map[string]*ssm.ParameterMetadata)
+	input := &ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("Path"),
+				Option: aws.String("Recursive"),
+				Values: []*string{aws.String(strings.TrimSuffix(prefix, "/"))},
+			},
+		},
+	}
+	if pageSize > 0 {
+		input.MaxResults = aws.Int64(pageSize)
+	}
+
+	err := c.definitions.DescribeParametersPages(input, func(output *ssm.DescribeParametersOutput, lastPage bool) bool {
+		for _, m := range output.Parameters {
+			metadata[*m.Name] = m
+		}
+		return true
+	})
+	return metadata, err
+}
+
+// summarizeRules lists the rules of an ACL per resource type
+func summarizeRules(rules string) (map[string][]string, error) {
+	summary := make(map[string][]string)
+	if strings.Contains(rules, "{{") {
+		summary["templated"] = []string{"rules are rendered at sync time"}
+		return summary, nil
+	}
+	parsed, err := parseRules(rules)
+	if err != nil {
+		return summary, errors.Cause(err)
+	}
+	for _, rule := range parsed {
+		if rule.Resource == "keyring" || rule.Resource == "operator" {
+			summary[rule.Resource] = append(summary[rule.Resource], rule.Policy)
+		} else {
+			summary[rule.Resource] = append(summary[rule.Resource], fmt.Sprintf("\"%s\" %s", rule.Segment, rule.Policy))
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,182 @@
+package acl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RevokeInput is the input for the Revoke function
+type RevokeInput struct {
+	// Sync selects and renders definitions as for Sync
+	Sync *SyncInput
+	// Prefix limits revocation to definitions at or under this path relative
+	// to the definition prefix, e.g. "team-a", all are revoked if empty
+	Prefix string
+	// AgentTokens maps agent token types to SSM parameters holding token IDs,
+	// agent tokens are re-installed if their parameter was revoked
+	AgentTokens map[string]string
+}
+
+// RevokedACL reports a revoked ACL. The ID of a legacy ACL is its secret,
+// so IDs are only identified by fingerprint.
+type RevokedACL struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Parameter string `json:"parameter"`
+	// Version is the version of the ID parameter holding the replacement
+	Version int64 `json:"version,omitempty"`
+	// OldFingerprint and NewFingerprint identify the old and new ID
+	OldFingerprint string `json:"old_fingerprint"`
+	NewFingerprint string `json:"new_fingerprint,omitempty"`
+	// Expired ACLs are destroyed without a replacement
+	Expired bool   `json:"expired,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Revoke destroys the ACLs whose IDs are stored under the selected ID
+// parameters and issues replacements from their definitions. Unlike
+// Rotate, old ACLs are destroyed before replacements are created.
+func (c *ClientSet) Revoke(i *RevokeInput) ([]RevokedACL, error) {
+	if i.Sync == nil {
+		return nil, errors.New("Sync is required")
+	}
+	aclDefinitionPrefix := ensureTrailingSlash(i.Sync.ACLDefinitionPrefix)
+	aclIDPrefix := ensureTrailingSlash(i.Sync.ACLIDPrefix)
+	if aclDefinitionPrefix == "" {
+		return nil, errors.New("ACLDefinitionPrefix is required")
+	}
+	if aclIDPrefix == "" {
+		return nil, errors.New("ACLIDPrefix is required")
+	}
+	// ID parameters are flat, so the group is taken from the definition path
+	prefix := strings.Trim(strings.TrimPrefix(i.Prefix, aclDefinitionPrefix), "/")
+	if i.Prefix != "" && prefix == "" {
+		return nil, errors.Errorf("Prefix \"%s\" doesn't name a path under the definition prefix", i.Prefix)
+	}
+
+	// the same targeting, expiry and guardrails as sync apply, and every
+	// selected ACL is rendered before anything is destroyed
+	acls, err := c.selectACLs(i.Sync, "", func(acl *aclItem) bool {
+		if !acl.idFromParam || !underPrefix(strings.TrimPrefix(acl.param, aclDefinitionPrefix), prefix) {
+			return false
+		}
+		if acl.Destroy {
+			log.Debugf("Skipping ACL %s (Name: \"%s\") - marked for destruction.", acl.slug, acl.Name)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(acls) == 0 {
+		return nil, errors.Errorf("No ACL IDs found for definitions under \"%s\"", i.Prefix)
+	}
+
+	var revokeErr error
+	revoked := make(map[string]bool)
+	var results []RevokedACL
+	failed := 0
+	for _, acl := range acls {
+		result := c.revokeACL(acl, aclIDPrefix)
+		if result.Error != "" {
+			failed++
+		} else if !result.Expired {
+			revoked[result.Parameter] = true
+		}
+		results = append(results, result)
+	}
+	if failed > 0 {
+		revokeErr = errors.Errorf("Failed to revoke %d of %d ACL(s)", failed, len(acls))
+	}
+
+	tokenTypes := make([]string, 0, len(i.AgentTokens))
+	for tokenType := range i.AgentTokens {
+		tokenTypes = append(tokenTypes, tokenType)
+	}
+	sort.Strings(tokenTypes)
+	for _, tokenType := range tokenTypes {
+		param := i.AgentTokens[tokenType]
+		if !revoked[param] {
+			log.Debugf("Skipping agent %s - SSM parameter \"%s\" wasn't revoked.", tokenType, param)
+			continue
+		}
+		if err := c.UpdateAgentTokenFromParameter(tokenType, param); err != nil {
+			log.Errorf("Failed to set agent %s: %s", tokenType, err.Error())
+			revokeErr = errors.Errorf("Failed to re-install agent %s", tokenType)
+			continue
+		}
+		log.Infof("Set agent %s from SSM parameter \"%s\"", tokenType, param)
+	}
+
+	return results, revokeErr
+}
+
+// revokeACL is a helper for Revoke and replaces a single ACL
+func (c *ClientSet) revokeACL(acl *aclItem, aclIDPrefix string) RevokedACL {
+	idParam := aclIDPrefix + acl.slug
+	result := RevokedACL{Slug: acl.slug, Name: acl.Name, Parameter: idParam, OldFingerprint: idFingerprint(acl.ID)}
+
+	// a deterministic ID derived later must not match the revoked one
+	if err := c.bumpIDGeneration(aclIDPrefix, acl.slug); err != nil {
+		result.Error = err.Error()
+		log.Errorf("Failed to revoke ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+		return result
+	}
+
+	log.Infof("Destroying ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+	if _, err := c.Consul.ACL().Destroy(acl.ID, nil); err != nil {
+		result.Error = errors.Wrap(err, "Failed to destroy ACL").Error()
+		log.Errorf("Failed to destroy ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+		return result
+	}
+
+	if acl.expired {
+		result.Expired = true
+		c.cleanupExpired(acl, aclIDPrefix)
+		return result
+	}
+
+	log.Infof("Creating replacement for ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+	id, _, err := c.Consul.ACL().Create(&consulapi.ACLEntry{
+		Name:  acl.Name,
+		Type:  acl.Type,
+		Rules: acl.Rules,
+	}, nil)
+	if err == nil {
+		result.Version, err = c.putStringParameterVersion(c.ids, idParam, id)
+		if err != nil {
+			if _, err := c.Consul.ACL().Destroy(id, nil); err != nil {
+				log.Errorf("Failed to clean up new ACL for %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+			}
+		}
+	}
+	if err != nil {
+		result.Error = errors.Wrap(err, "Failed to issue replacement ACL").Error()
+		log.Errorf("Failed to issue replacement for ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+		// a later sync would otherwise recreate the ACL with the revoked ID,
+		// or derive a new deterministic ID as the generation was bumped
+		if _, err := c.ids.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(idParam)}); err != nil {
+			log.Errorf("Failed to delete SSM parameter \"%s\": %s", idParam, err.Error())
+		}
+		return result
+	}
+
+	result.NewFingerprint = idFingerprint(id)
+	return result
+}
+
+// underPrefix determines if a parameter is, or is under, a prefix,
+// every parameter is under an empty prefix
+func underPrefix(param, prefix string) bool {
+	if prefix == "" || param == prefix {
+		return true
+	}
+	return strings.HasPrefix(param, ensureTrailingSlash(prefix))
+}
@@ -0,0 +1,151 @@
+package acl
+
+import (
+	"sort"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RotateInput is the input for the Rotate function
+type RotateInput struct {
+	// Sync selects and renders definitions as for Sync
+	Sync *SyncInput
+	// Slug limits rotation to a single ACL, all ACLs are rotated if empty
+	Slug string
+	// AgentTokens maps agent token types to SSM parameters holding token IDs,
+	// agent tokens are re-installed if their parameter was rotated
+	AgentTokens map[string]string
+	GracePeriod time.Duration
+}
+
+// Rotate replaces the token of ACLs whose IDs are stored under the ID prefix.
+// New ACLs are created from the rendered definitions, as sync would, their IDs
+// written to SSM, and the old ACLs destroyed once the grace period has elapsed.
+func (c *ClientSet) Rotate(i *RotateInput) error {
+	if i.Sync == nil {
+		return errors.New("Sync is required")
+	}
+	aclDefinitionPrefix := ensureTrailingSlash(i.Sync.ACLDefinitionPrefix)
+	aclIDPrefix := ensureTrailingSlash(i.Sync.ACLIDPrefix)
+	if aclDefinitionPrefix == "" {
+		return errors.New("ACLDefinitionPrefix is required")
+	}
+	if aclIDPrefix == "" {
+		return errors.New("ACLIDPrefix is required")
+	}
+
+	found := false
+	acls, err := c.selectACLs(i.Sync, "", func(acl *aclItem) bool {
+		if i.Slug != "" && acl.slug != i.Slug {
+			return false
+		}
+		found = true
+
+		if acl.Destroy {
+			log.Debugf("Skipping ACL %s (Name: \"%s\") - marked for destruction.", acl.slug, acl.Name)
+			return false
+		}
+		if !acl.idFromParam {
+			if acl.ID == "" {
+				log.Warnf("Unable to rotate ACL %s (Name: \"%s\"), no ID parameter found.", acl.slug, acl.Name)
+			} else {
+				log.Warnf("Unable to rotate ACL %s (Name: \"%s\"), ID is set in the definition.", acl.slug, acl.Name)
+			}
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if i.Slug != "" && !found {
+		return errors.Errorf("No ACL definition found for \"%s\"", i.Slug)
+	}
+
+	var retired []*aclItem
+	rotated := make(map[string]bool)
+	var rotateErr error
+
+	for _, acl := range acls {
+		if acl.expired {
+			log.Warnf("Unable to rotate ACL %s (Name: \"%s\"), it has expired.", acl.slug, acl.Name)
+			continue
+		}
+
+		log.Infof("Rotating ACL %s (Name: \"%s\").", acl.slug, acl.Name)
+
+		currentACL, _, err := c.Consul.ACL().Info(acl.ID, nil)
+		if err != nil {
+			rotateErr = errors.Wrapf(err, "Failed to get info for ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+			break
+		}
+		if currentACL == nil {
+			log.Warnf("Unable to rotate ACL %s (Name: \"%s\"), no ACL found with the stored ID.", acl.slug, acl.Name)
+			continue
+		}
+
+		// the new ACL comes from the definition, so changes made by hand
+		// to the current ACL are not carried over
+		newID, _, err := c.Consul.ACL().Create(&consulapi.ACLEntry{
+			Name:  acl.Name,
+			Type:  acl.Type,
+			Rules: acl.Rules,
+		}, nil)
+		if err != nil {
+			rotateErr = errors.Wrapf(err, "Failed to create new ACL for %s (Name: \"%s\")", acl.slug, acl.Name)
+			break
+		}
+
+		// previous ID remains available in the parameter's version history
+		idParam := aclIDPrefix + acl.slug
+		if err := c.putStringParameter(c.ids, idParam, newID); err != nil {
+			rotateErr = errors.Wrapf(err, "Failed to save new ID for ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+			if _, err := c.Consul.ACL().Destroy(newID, nil); err != nil {
+				log.Errorf("Failed to clean up new ACL for %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+			}
+			break
+		}
+		rotated[idParam] = true
+		retired = append(retired, acl)
+
+		if err := c.bumpIDGeneration(aclIDPrefix, acl.slug); err != nil {
+			rotateErr = err
+			break
+		}
+	}
+
+	tokenTypes := make([]string, 0, len(i.AgentTokens))
+	for tokenType := range i.AgentTokens {
+		tokenTypes = append(tokenTypes, tokenType)
+	}
+	sort.Strings(tokenTypes)
+	for _, tokenType := range tokenTypes {
+		param := i.AgentTokens[tokenType]
+		if !rotated[param] {
+			log.Debugf("Skipping agent %s - SSM parameter \"%s\" wasn't rotated.", tokenType, param)
+			continue
+		}
+		if err := c.UpdateAgentTokenFromParameter(tokenType, param); err != nil {
+			log.Errorf("Failed to set agent %s: %s", tokenType, err.Error())
+			continue
+		}
+		log.Infof("Set agent %s from SSM parameter \"%s\"", tokenType, param)
+	}
+
+	if len(retired) > 0 && i.GracePeriod > 0 {
+		log.Infof("Waiting %s before destroying %d old ACL(s).", i.GracePeriod, len(retired))
+		time.Sleep(i.GracePeriod)
+	}
+
+	for _, acl := range retired {
+		log.Infof("Destroying old ACL for %s (Name: \"%s\").", acl.slug, acl.Name)
+		if _, err := c.Consul.ACL().Destroy(acl.ID, nil); err != nil {
+			log.Errorf("Failed to delete old ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+		}
+	}
+
+	return rotateErr
+}
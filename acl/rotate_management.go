@@ -0,0 +1,87 @@
+package acl
+
+import (
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RotateManagement replaces the management token stored in an SSM parameter.
+// A new management token is created with the current one, verified, saved to
+// the parameter, and the old token (e.g. the bootstrap token) is destroyed.
+func (c *ClientSet) RotateManagement(consulTokenParam, name string) (string, error) {
+	if consulTokenParam == "" {
+		return "", errors.New("consulTokenParam cannot be empty")
+	}
+	oldID := c.consulToken
+	if oldID == "" {
+		return "", errors.Errorf("No management token found in SSM parameter \"%s\"", consulTokenParam)
+	}
+
+	oldACL, _, err := c.Consul.ACL().Info(oldID, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to look up current management token")
+	}
+	if oldACL == nil || oldACL.Type != consulapi.ACLManagementType {
+		return "", errors.Errorf("Token in SSM parameter \"%s\" is not a management token", consulTokenParam)
+	}
+
+	newID, _, err := c.Consul.ACL().Create(&consulapi.ACLEntry{
+		Name: name,
+		Type: consulapi.ACLManagementType,
+	}, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create new management token")
+	}
+	log.Infof("Created new management token (Name: \"%s\").", name)
+
+	newClient, err := c.newConsulClient(newID)
+	if err != nil {
+		c.destroyUnusedToken(newID)
+		return "", errors.Wrap(err, "Failed to create Consul client with new management token")
+	}
+	if err := verifyManagementToken(newClient, newID); err != nil {
+		c.destroyUnusedToken(newID)
+		return "", errors.Wrap(err, "Failed to verify new management token")
+	}
+
+	if err := c.putStringParameter(c.tokens, consulTokenParam, newID); err != nil {
+		c.destroyUnusedToken(newID)
+		return "", errors.Wrapf(err, "Failed to save new management token to SSM parameter \"%s\"", consulTokenParam)
+	}
+	log.Infof("Saved new management token to SSM parameter \"%s\".", consulTokenParam)
+
+	c.Consul = newClient
+	c.consulToken = newID
+
+	if _, err := c.Consul.ACL().Destroy(oldID, nil); err != nil {
+		return newID, errors.Wrapf(err, "Failed to destroy old management token (Name: \"%s\")", oldACL.Name)
+	}
+	log.Infof("Destroyed old management token (Name: \"%s\").", oldACL.Name)
+
+	return newID, nil
+}
+
+// destroyUnusedToken cleans up a new token that could not be put into use
+func (c *ClientSet) destroyUnusedToken(id string) {
+	if _, err := c.Consul.ACL().Destroy(id, nil); err != nil {
+		log.Errorf("Failed to clean up new management token: %s", err.Error())
+	}
+}
+
+// verifyManagementToken checks the given client's token has management privileges
+func verifyManagementToken(client *consulapi.Client, id string) error {
+	entry, _, err := client.ACL().Info(id, nil)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.Type != consulapi.ACLManagementType {
+		return errors.New("token is not a management token")
+	}
+
+	// listing ACLs requires management privileges
+	if _, _, err := client.ACL().List(nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package acl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/pkg/errors"
+)
+
+const (
+	policyDeny  = "deny"
+	policyRead  = "read"
+	policyWrite = "write"
+	policyList  = "list"
+)
+
+// rulePolicy mirrors the rules accepted by Consul's legacy ACL system
+type rulePolicy struct {
+	Agents          []*segmentPolicy `hcl:"agent,expand"`
+	Keys            []*segmentPolicy `hcl:"key,expand"`
+	Nodes           []*segmentPolicy `hcl:"node,expand"`
+	Services        []*segmentPolicy `hcl:"service,expand"`
+	Sessions        []*segmentPolicy `hcl:"session,expand"`
+	Events          []*segmentPolicy `hcl:"event,expand"`
+	PreparedQueries []*segmentPolicy `hcl:"query,expand"`
+	Keyring         string           `hcl:"keyring"`
+	Operator        string           `hcl:"operator"`
+}
+
+// segmentPolicy is the policy for a resource segment, e.g. a key prefix
+type segmentPolicy struct {
+	Segment string `hcl:",key"`
+	Policy  string
+}
+
+// aclRule is a single resource rule, Segment is empty for keyring and operator
+type aclRule struct {
+	Resource string
+	Segment  string
+	Policy   string
+}
+
+// String returns the rule in HCL form
+func (r aclRule) String() string {
+	if r.Resource == "keyring" || r.Resource == "operator" {
+		return fmt.Sprintf("%s = \"%s\"", r.Resource, r.Policy)
+	}
+	return fmt.Sprintf("%s \"%s\" { policy = \"%s\" }", r.Resource, r.Segment, r.Policy)
+}
+
+// ruleResources are the resource types known to Consul's legacy ACL system
+var ruleResources = []string{"agent", "event", "key", "keyring", "node", "operator", "query", "service", "session"}
+
+// parseRules parses and validates ACL rules, returning them as a flat list
+func parseRules(rules string) ([]aclRule, error) {
+	if rules == "" {
+		return nil, nil
+	}
+
+	file, err := hcl.Parse(rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse ACL rules")
+	}
+
+	// the decoder ignores unknown keys, check them first
+	if list, ok := file.Node.(*ast.ObjectList); ok {
+		for _, item := range list.Items {
+			if len(item.Keys) == 0 {
+				continue
+			}
+			resource := item.Keys[0].Token.Value()
+			if s, ok := resource.(string); !ok || !contains(ruleResources, s) {
+				return nil, errors.Errorf("Unknown ACL resource type %s", item.Keys[0].Token.Text)
+			}
+		}
+	}
+
+	var p rulePolicy
+	if err := hcl.DecodeObject(&p, file); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode ACL rules")
+	}
+
+	var parsed []aclRule
+	segments := []struct {
+		resource string
+		policies []*segmentPolicy
+	}{
+		{"agent", p.Agents},
+		{"event", p.Events},
+		{"key", p.Keys},
+		{"node", p.Nodes},
+		{"query", p.PreparedQueries},
+		{"service", p.Services},
+		{"session", p.Sessions},
+	}
+	for _, s := range segments {
+		for _, sp := range s.policies {
+			if !isPolicyValid(s.resource, sp.Policy) {
+				return nil, errors.Errorf("Invalid %s policy \"%s\" for \"%s\"", s.resource, sp.Policy, sp.Segment)
+			}
+			parsed = append(parsed, aclRule{Resource: s.resource, Segment: sp.Segment, Policy: sp.Policy})
+		}
+	}
+	for resource, policy := range map[string]string{"keyring": p.Keyring, "operator": p.Operator} {
+		if policy == "" {
+			continue
+		}
+		if !isPolicyValid(resource, policy) {
+			return nil, errors.Errorf("Invalid %s policy \"%s\"", resource, policy)
+		}
+		parsed = append(parsed, aclRule{Resource: resource, Policy: policy})
+	}
+
+	sort.SliceStable(parsed, func(a, b int) bool {
+		return parsed[a].Resource < parsed[b].Resource
+	})
+	return parsed, nil
+}
+
+// isPolicyValid returns whether a policy is allowed for a resource type
+func isPolicyValid(resource, policy string) bool {
+	switch policy {
+	case policyDeny, policyRead, policyWrite:
+		return true
+	case policyList:
+		return resource == "key"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   string
+		want    []string
+		wantErr string
+	}{
+		{name: "empty", rules: ""},
+		{
+			name:  "segments",
+			rules: `key "app/" { policy = "write" } service "" { policy = "read" } key "" { policy = "list" }`,
+			want: []string{
+				`key "app/" { policy = "write" }`,
+				`key "" { policy = "list" }`,
+				`service "" { policy = "read" }`,
+			},
+		},
+		{
+			name:  "keyring and operator",
+			rules: "operator = \"read\"\nkeyring = \"write\"\nnode \"web\" { policy = \"deny\" }",
+			want: []string{
+				`keyring = "write"`,
+				`node "web" { policy = "deny" }`,
+				`operator = "read"`,
+			},
+		},
+		{
+			name:  "all segment resources",
+			rules: `agent "" { policy = "read" } event "" { policy = "read" } query "" { policy = "read" } session "" { policy = "read" }`,
+			want: []string{
+				`agent "" { policy = "read" }`,
+				`event "" { policy = "read" }`,
+				`query "" { policy = "read" }`,
+				`session "" { policy = "read" }`,
+			},
+		},
+		{name: "syntax", rules: `key "app/" { policy = `, wantErr: "Failed to parse ACL rules"},
+		{name: "unknown resource", rules: `acl = "write"`, wantErr: "Unknown ACL resource type acl"},
+		{name: "invalid policy", rules: `key "" { policy = "admin" }`, wantErr: `Invalid key policy "admin"`},
+		{name: "list on service", rules: `service "" { policy = "list" }`, wantErr: `Invalid service policy "list"`},
+		{name: "invalid operator", rules: `operator = "list"`, wantErr: `Invalid operator policy "list"`},
+	}
+	for _, tc := range cases {
+		rules, err := parseRules(tc.rules)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, rule := range rules {
+			got = append(got, rule.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.name, strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestIsPolicyValid(t *testing.T) {
+	cases := []struct {
+		resource string
+		policy   string
+		want     bool
+	}{
+		{"key", policyDeny, true},
+		{"key", policyRead, true},
+		{"key", policyWrite, true},
+		{"key", policyList, true},
+		{"service", policyList, false},
+		{"operator", policyWrite, true},
+		{"keyring", policyList, false},
+		{"node", "", false},
+		{"node", "admin", false},
+	}
+	for _, tc := range cases {
+		if got := isPolicyValid(tc.resource, tc.policy); got != tc.want {
+			t.Errorf("isPolicyValid(%q, %q) = %t, want %t", tc.resource, tc.policy, got, tc.want)
+		}
+	}
+}
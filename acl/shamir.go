@@ -0,0 +1,134 @@
+package acl
+
+import (
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// expTable and logTable hold powers and logarithms of the generator 3 in
+// GF(2^8) with the AES polynomial, used for Shamir's secret sharing
+var expTable, logTable [256]byte
+
+func init() {
+	x := byte(1)
+	for n := 0; n < 255; n++ {
+		expTable[n] = x
+		logTable[x] = byte(n)
+		// multiply by 3, i.e. x*2 + x
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	expTable[255] = expTable[0]
+}
+
+// gfMul multiplies two elements of GF(2^8)
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+// gfDiv divides two elements of GF(2^8), b must not be zero
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// splitSecret splits a secret into n shares, any k of which recover it.
+// Each share holds one byte per secret byte followed by its x coordinate.
+func splitSecret(secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("Secret cannot be empty")
+	}
+	if k < 2 || n < k || n > 255 {
+		return nil, errors.Errorf("Invalid shares %d and threshold %d, need 2 <= threshold <= shares <= 255", n, k)
+	}
+
+	// distinct, non-zero x coordinates in random order
+	xs := make([]byte, 255)
+	for x := range xs {
+		xs[x] = byte(x + 1)
+	}
+	random := make([]byte, 255)
+	if _, err := rand.Read(random); err != nil {
+		return nil, errors.Wrap(err, "Failed to read random bytes")
+	}
+	for a := len(xs) - 1; a > 0; a-- {
+		b := int(random[a]) % (a + 1)
+		xs[a], xs[b] = xs[b], xs[a]
+	}
+
+	shares := make([][]byte, n)
+	for s := range shares {
+		shares[s] = make([]byte, len(secret)+1)
+		shares[s][len(secret)] = xs[s]
+	}
+
+	coefficients := make([]byte, k)
+	for b, value := range secret {
+		coefficients[0] = value
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, errors.Wrap(err, "Failed to read random bytes")
+		}
+		for s := range shares {
+			// Horner's method
+			x := xs[s]
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			shares[s][b] = y
+		}
+	}
+	return shares, nil
+}
+
+// combineShares recovers a secret from shares made by splitSecret,
+// the result is only correct if at least the threshold of shares is given
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("At least two shares are required")
+	}
+	length := len(shares[0])
+	if length < 2 {
+		return nil, errors.New("Shares are too short")
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool)
+	for s, share := range shares {
+		if len(share) != length {
+			return nil, errors.New("Shares differ in length")
+		}
+		x := share[length-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("Shares must be distinct")
+		}
+		seen[x] = true
+		xs[s] = x
+	}
+
+	secret := make([]byte, length-1)
+	for b := range secret {
+		// Lagrange interpolation at x = 0
+		var value byte
+		for s := range shares {
+			basis := byte(1)
+			for o := range shares {
+				if o != s {
+					basis = gfMul(basis, gfDiv(xs[o], xs[o]^xs[s]))
+				}
+			}
+			value ^= gfMul(shares[s][b], basis)
+		}
+		secret[b] = value
+	}
+	return secret, nil
+}
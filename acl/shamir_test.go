@@ -0,0 +1,158 @@
+package acl
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombineRoundTrip(t *testing.T) {
+	secret := []byte("b1d5f6a0-3c2e-4f7a-9d8b-1e2f3a4b5c6d")
+	cases := []struct {
+		n, k int
+	}{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{7, 7},
+		{255, 2},
+	}
+	for _, tc := range cases {
+		shares, err := splitSecret(secret, tc.n, tc.k)
+		if err != nil {
+			t.Fatalf("splitSecret(%d, %d): %s", tc.n, tc.k, err)
+		}
+		if len(shares) != tc.n {
+			t.Fatalf("splitSecret(%d, %d): got %d shares", tc.n, tc.k, len(shares))
+		}
+		// any k shares, in any order, recover the secret
+		for trial := 0; trial < 10; trial++ {
+			perm := rand.Perm(tc.n)[:tc.k]
+			subset := make([][]byte, tc.k)
+			for s, p := range perm {
+				subset[s] = shares[p]
+			}
+			got, err := combineShares(subset)
+			if err != nil {
+				t.Fatalf("combineShares(%d of %d): %s", tc.k, tc.n, err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Fatalf("combineShares(%d of %d) = %q, want %q", tc.k, tc.n, got, secret)
+			}
+		}
+	}
+}
+
+func TestSplitSecretInvalid(t *testing.T) {
+	cases := []struct {
+		secret []byte
+		n, k   int
+	}{
+		{nil, 3, 2},
+		{[]byte("x"), 3, 1},
+		{[]byte("x"), 2, 3},
+		{[]byte("x"), 256, 2},
+	}
+	for _, tc := range cases {
+		if _, err := splitSecret(tc.secret, tc.n, tc.k); err == nil {
+			t.Errorf("splitSecret(%q, %d, %d): expected error", tc.secret, tc.n, tc.k)
+		}
+	}
+}
+
+func TestCombineSharesBelowThreshold(t *testing.T) {
+	secret := []byte("management-token")
+	shares, err := splitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := combineShares(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Fatal("combineShares recovered the secret from fewer than the threshold of shares")
+	}
+}
+
+func TestCombineSharesInvalid(t *testing.T) {
+	shares, err := splitSecret([]byte("management-token"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"one share", shares[:1]},
+		{"duplicate", [][]byte{shares[0], shares[0]}},
+		{"different lengths", [][]byte{shares[0], shares[1][1:]}},
+		{"too short", [][]byte{{1}, {2}}},
+		{"zero x", [][]byte{shares[0], append(append([]byte{}, shares[1][:len(shares[1])-1]...), 0)}},
+	}
+	for _, tc := range cases {
+		if _, err := combineShares(tc.shares); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestRecoverToken(t *testing.T) {
+	token := "b1d5f6a0-3c2e-4f7a-9d8b-1e2f3a4b5c6d"
+	encoded := encodeShares(t, token, 3, 2)
+	other := encodeShares(t, "another-token", 3, 2)
+
+	// flip a byte of the first share's value
+	k, sum, share, err := decodeShare(encoded[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	share[0] ^= 0xff
+	tampered := encodeShare(k, sum, share)
+
+	cases := []struct {
+		name   string
+		values []string
+		err    string
+	}{
+		{"threshold", encoded[:2], ""},
+		{"all shares", encoded, ""},
+		{"whitespace", []string{" " + encoded[0] + "\n", encoded[2]}, ""},
+		{"too few", encoded[:1], "escrow shares are required"},
+		{"none", nil, "escrow shares are required"},
+		{"duplicate", []string{encoded[0], encoded[0]}, "Failed to combine"},
+		{"tampered", []string{tampered, encoded[1]}, "checksum"},
+		{"different escrows", []string{encoded[0], other[1]}, "different escrows"},
+		{"malformed", []string{"2:abc", encoded[1]}, "Invalid escrow share 1"},
+		{"bad threshold", []string{"1:abc:AAAA", encoded[1]}, "Invalid escrow share 1"},
+		{"bad base64", []string{encoded[0], "2:" + sum + ":!!"}, "Invalid escrow share 2"},
+	}
+	for _, tc := range cases {
+		got, err := recoverToken(tc.values)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tc.name, err)
+			} else if got != token {
+				t.Errorf("%s: got %q, want %q", tc.name, got, token)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// encodeShares splits a token and encodes its shares as Escrow does
+func encodeShares(t *testing.T, token string, n, k int) []string {
+	shares, err := splitSecret([]byte(token), n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded []string
+	for _, share := range shares {
+		encoded = append(encoded, encodeShare(k, escrowChecksum(token), share))
+	}
+	return encoded
+}
@@ -1,8 +1,8 @@
 package acl
 
 import (
-	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -17,13 +17,42 @@ type SyncInput struct {
 	ACLIDPrefix         string
 	PageSize            int64
 	OnlyIfConsulLeader  bool
+	IDKeyParam          string
+	// Environment and TargetLabels select definitions limited
+	// to particular environments or labels
+	Environment  string
+	TargetLabels map[string]string
+	// Vars are the variables available to templated definitions
+	Vars           map[string]string
+	TemplateAccess TemplateAccess
+	// Guardrails are optionally checked before any ACL is applied
+	Guardrails *Guardrails
+	// ExpiryWarning is how long before expiry to warn about expiring ACLs
+	ExpiryWarning time.Duration
+	// BreakGlassParam is deleted once its break-glass token has expired
+	BreakGlassParam string
 }
 
 // aclItem is the internal representation of an ACL
 type aclItem struct {
 	consulapi.ACLEntry
-	Destroy bool `json:",string"`
-	slug    string
+	Destroy      bool `json:",string"`
+	Datacenters  []string
+	Environments []string
+	Labels       map[string]string
+	Include      []string
+	Owner        string
+	Team         string
+	Ticket       string
+	Description  string
+	ExpiresAt    string
+	TTL          string
+	param        string
+	slug         string
+	generatedID  bool
+	idFromParam  bool
+	expired      bool
+	createdAt    time.Time
 }
 
 // Sync syncronizes ACLS with AWS SSM
@@ -45,66 +74,207 @@ func (c *ClientSet) Sync(i *SyncInput) error {
 		}
 	}
 
-	pageNum := 0
-	params := &ssm.GetParametersByPathInput{
-		Path:           aws.String(aclDefinitionPrefix),
-		Recursive:      aws.Bool(true),
-		WithDecryption: aws.Bool(true),
+	// expired break-glass ACLs are destroyed whether or not their parameter is known
+	if n, err := c.RevokeBreakGlass(i.BreakGlassParam, true); err != nil {
+		log.Errorf("Failed to revoke expired break-glass ACLs: %s", err.Error())
+	} else if n > 0 {
+		log.Infof("Revoked %d expired break-glass ACL(s).", n)
 	}
-	if i.PageSize > 0 {
-		params.MaxResults = aws.Int64(i.PageSize)
+
+	// a secret key enables deterministic IDs for definitions without one
+	var idKey string
+	if i.IDKeyParam != "" {
+		val, err := c.getStringParameter(c.definitions, i.IDKeyParam, true)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get ID key from SSM parameter \"%s\"", i.IDKeyParam)
+		}
+		idKey = *val
 	}
 
-	fn := func(output *ssm.GetParametersByPathOutput, lastPage bool) bool {
-		pageNum++
-		log.Debugf("GetParametersByPathPages page: %d, lastPage?: %t", pageNum, lastPage)
-		for _, item := range output.Parameters {
-			acl := c.parameterToACL(item, aclDefinitionPrefix, aclIDPrefix)
-			c.manageACL(acl, aclDefinitionPrefix, aclIDPrefix)
+	acls, err := c.selectACLs(i, idKey, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, acl := range acls {
+		if acl.expired && acl.ID == "" {
+			log.Debugf("Skipping ACL %s (Name: \"%s\") - expired.", acl.slug, acl.Name)
+			continue
+		}
+
+		if err := c.manageACL(acl, aclDefinitionPrefix, aclIDPrefix); err != nil {
+			return err
+		}
+
+		if acl.expired {
+			c.cleanupExpired(acl, aclIDPrefix)
+		}
+
+		// an ID parameter exists unless the ID is set in the definition
+		if aclIDPrefix != "" && !acl.Destroy && (acl.idFromParam || acl.generatedID || acl.ID == "") {
+			if err := c.tagIDParameter(acl, aclIDPrefix+acl.slug); err != nil {
+				log.Errorf("Failed to tag ID parameter of ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// selectACLs is a helper for Sync and Revoke and returns the ACLs which apply
+// to the target, with their expiry checked and templates rendered. The ACLs
+// are collected first so nothing is applied unless all of them pass the
+// guardrails. If filter is given, only ACLs it accepts are selected.
+func (c *ClientSet) selectACLs(i *SyncInput, idKey string, filter func(*aclItem) bool) ([]*aclItem, error) {
+	aclDefinitionPrefix := ensureTrailingSlash(i.ACLDefinitionPrefix)
+	aclIDPrefix := ensureTrailingSlash(i.ACLIDPrefix)
+
+	// only look up the datacenter once, and only if a definition needs it
+	var dc string
+	datacenter := func() (string, error) {
+		if dc != "" {
+			return dc, nil
+		}
+		var err error
+		dc, err = c.datacenter()
+		return dc, err
+	}
+
+	renderer := c.newTemplateRenderer(i.Vars, i.Environment, datacenter, i.TemplateAccess)
+
+	var acls []*aclItem
+	var selectErr error
+	err := c.forEachDefinition(aclDefinitionPrefix, i.PageSize, func(param *ssm.Parameter, lib *definitionLibrary) {
+		if selectErr != nil {
+			return
+		}
+		acl, err := c.parameterToACL(param, lib, aclIDPrefix, idKey)
+		if err != nil {
+			selectErr = err
+			return
+		}
+		if filter != nil && !filter(acl) {
+			return
+		}
+
+		applies, reason, err := acl.appliesTo(datacenter, i.Environment, i.TargetLabels)
+		if err != nil {
+			selectErr = err
+			return
+		}
+		if !applies {
+			log.Infof("Skipping ACL %s (Name: \"%s\") - %s.", acl.slug, acl.Name, reason)
+			return
+		}
+
+		if err := c.checkExpiry(acl, aclIDPrefix, i.ExpiryWarning); err != nil {
+			selectErr = err
+			return
+		}
+
+		if err := renderer.render(acl); err != nil {
+			selectErr = err
+			return
+		}
+
+		// sync destroys expired ACLs with a break-glass name
+		if !acl.Destroy && strings.HasPrefix(acl.Name, breakGlassNamePrefix) {
+			selectErr = errors.Errorf("ACL %s (Name: \"%s\") uses a name reserved for break-glass ACLs", acl.slug, acl.Name)
+			return
+		}
+
+		acls = append(acls, acl)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get ACL definition parameters from prefix \"%s\"", aclDefinitionPrefix)
+	}
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	if i.Guardrails != nil {
+		if err := i.Guardrails.enforce(acls); err != nil {
+			return nil, err
 		}
-		return true
 	}
+	return acls, nil
+}
 
-	err := c.SSM.GetParametersByPathPages(params, fn)
+// forEachDefinition calls fn for every ACL definition under the given prefix.
+// All parameters are read first so fragments and defaults are available
+// to every definition.
+func (c *ClientSet) forEachDefinition(prefix string, pageSize int64, fn func(*ssm.Parameter, *definitionLibrary)) error {
+	items, err := c.getDefinitionParameters(prefix, pageSize)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to get ACL definition parameters from prefix \"%s\"", aclDefinitionPrefix)
+		return err
 	}
 
+	lib, err := newDefinitionLibrary(prefix, items)
+	if err != nil {
+		return err
+	}
+	for _, item := range lib.definitions {
+		fn(item, lib)
+	}
 	return nil
 }
 
-// parameterToACL is a helper for Sync and converts an SSM parameter to an aclItem
-func (c *ClientSet) parameterToACL(param *ssm.Parameter, aclDefinitionPrefix, aclIDPrefix string) *aclItem {
-	var acl aclItem
-	parts := strings.Split(strings.TrimPrefix(*param.Name, aclDefinitionPrefix), "/")
-	acl.slug = parts[len(parts)-1]
-	log.Debugf("Got SSM parameter name: %s, value: %s, slug: %s", *param.Name, *param.Value, acl.slug)
+// getDefinitionParameters returns every SSM parameter under the given prefix
+func (c *ClientSet) getDefinitionParameters(prefix string, pageSize int64) ([]*ssm.Parameter, error) {
+	pageNum := 0
+	params := &ssm.GetParametersByPathInput{
+		Path:           aws.String(prefix),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+	if pageSize > 0 {
+		params.MaxResults = aws.Int64(pageSize)
+	}
+
+	var items []*ssm.Parameter
+	err := c.definitions.GetParametersByPathPages(params, func(output *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		pageNum++
+		log.Debugf("GetParametersByPathPages page: %d, lastPage?: %t", pageNum, lastPage)
+		items = append(items, output.Parameters...)
+		return true
+	})
+	return items, err
+}
 
-	err := json.Unmarshal([]byte(*param.Value), &acl)
+// parameterToACL is a helper for Sync and converts an SSM parameter to an aclItem
+func (c *ClientSet) parameterToACL(param *ssm.Parameter, lib *definitionLibrary, aclIDPrefix, idKey string) (*aclItem, error) {
+	acl, err := lib.parseDefinition(param)
 	if err != nil {
-		log.Fatalf("Failed to parse parameter %s from %s as acl: %s", acl.slug, *param.Name, err.Error())
+		return nil, err
 	}
 
 	// if ID not provided, attempt to get it from <aclIDPrefix>/slug
 	if acl.ID == "" {
 		idParam := aclIDPrefix + acl.slug
-		if val, err := c.GetStringParameter(idParam, false); err != nil {
-			log.Fatalf("Failed to get ACL ID from SSM parameter \"%s\": %s", idParam, err.Error())
-		} else {
-			acl.ID = *val
+		val, err := c.getStringParameter(c.ids, idParam, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get ACL ID from SSM parameter \"%s\"", idParam)
 		}
+		acl.ID = *val
+		acl.idFromParam = acl.ID != ""
 	}
 
-	// Type should default to client
-	if acl.Type == "" {
-		acl.Type = "client"
+	// if ID still not known, derive it from the slug when an ID key is given
+	if acl.ID == "" && idKey != "" {
+		generation, err := c.idGeneration(aclIDPrefix, acl.slug)
+		if err != nil {
+			return nil, err
+		}
+		acl.ID = deterministicID(idKey, acl.slug, generation)
+		acl.generatedID = true
+		log.Debugf("Using deterministic ID for ACL %s", acl.slug)
 	}
 
-	return &acl
+	return acl, nil
 }
 
 // manageACL is a helper for Sync and manages a particular ACL item
-func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix string) {
+func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix string) error {
 	if acl.ID == "" {
 		// we are working on an ACL without an ID provided
 
@@ -120,10 +290,12 @@ func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix str
 				Rules: acl.Rules,
 			}, nil)
 			if err != nil {
-				log.Fatalf("Failed to create ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+				return errors.Wrapf(err, "Failed to create ACL %s (Name: \"%s\")", acl.slug, acl.Name)
 			}
 
-			c.PutStringParameter(aclIDPrefix+acl.slug, id)
+			if err := c.putStringParameter(c.ids, aclIDPrefix+acl.slug, id); err != nil {
+				return errors.Wrapf(err, "Failed to save ID of ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+			}
 
 		}
 	} else {
@@ -131,7 +303,7 @@ func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix str
 
 		currentACL, _, err := c.Consul.ACL().Info(acl.ID, nil)
 		if err != nil {
-			log.Fatalf("Failed to get info for ACL %s (Name: \"%s\") ", acl.slug, acl.Name)
+			return errors.Wrapf(err, "Failed to get info for ACL %s (Name: \"%s\")", acl.slug, acl.Name)
 		}
 
 		if currentACL == nil {
@@ -150,13 +322,26 @@ func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix str
 					Rules: acl.Rules,
 				}, nil)
 				if err != nil {
-					log.Fatalf("Failed to create ACL %s (Name: \"%s\"): %s", acl.slug, acl.Name, err.Error())
+					return errors.Wrapf(err, "Failed to create ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+				}
+
+				if acl.generatedID {
+					if err := c.putStringParameter(c.ids, aclIDPrefix+acl.slug, acl.ID); err != nil {
+						return errors.Wrapf(err, "Failed to save ID of ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+					}
 				}
 			}
 
 		} else {
 			// we are working on an ACL with an ID, and we have an existing ACL that matches that ID
 
+			if acl.generatedID && !acl.Destroy {
+				// restore the ID parameter, it was missing or we wouldn't have generated the ID
+				if err := c.putStringParameter(c.ids, aclIDPrefix+acl.slug, acl.ID); err != nil {
+					return errors.Wrapf(err, "Failed to save ID of ACL %s (Name: \"%s\")", acl.slug, acl.Name)
+				}
+			}
+
 			if acl.Destroy {
 				log.Infof("Destroying ACL %s (Name: \"%s\").", acl.slug, acl.Name)
 
@@ -182,6 +367,7 @@ func (c *ClientSet) manageACL(acl *aclItem, aclDefinitionPrefix, aclIDPrefix str
 			}
 		}
 	}
+	return nil
 }
 
 // ensureTrailingSlash ensures the given string ends in "/"
@@ -0,0 +1,62 @@
+package acl
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// appliesTo determines whether a definition applies to the cluster being
+// synchronized, returning the reason if it does not. The datacenter is
+// only looked up if the definition is limited to particular datacenters.
+func (acl *aclItem) appliesTo(datacenter func() (string, error), environment string, labels map[string]string) (bool, string, error) {
+	if len(acl.Environments) > 0 && !contains(acl.Environments, environment) {
+		return false, fmt.Sprintf("environment \"%s\" not targeted", environment), nil
+	}
+
+	for k, v := range acl.Labels {
+		if labels[k] != v {
+			return false, fmt.Sprintf("label %s=%s not matched", k, v), nil
+		}
+	}
+
+	if len(acl.Datacenters) > 0 {
+		dc, err := datacenter()
+		if err != nil {
+			return false, "", err
+		}
+		if !contains(acl.Datacenters, dc) {
+			return false, fmt.Sprintf("datacenter \"%s\" not targeted", dc), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// datacenter returns the Consul datacenter being managed, either as
+// configured or as reported by the agent
+func (c *ClientSet) datacenter() (string, error) {
+	if c.consulInput.Datacenter != "" {
+		return c.consulInput.Datacenter, nil
+	}
+
+	resp, err := c.Consul.Agent().Self()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to determine Consul datacenter")
+	}
+	dc, ok := resp["Config"]["Datacenter"].(string)
+	if !ok {
+		return "", errors.New("Failed to parse /agent/self config")
+	}
+	return dc, nil
+}
+
+// contains determines whether a list of strings contains a value
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
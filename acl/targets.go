@@ -0,0 +1,106 @@
+package acl
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Target is a Consul cluster to synchronize ACLs to
+type Target struct {
+	Name             string `json:"name" mapstructure:"name"`
+	Address          string `json:"address" mapstructure:"address"`
+	Datacenter       string `json:"datacenter" mapstructure:"datacenter"`
+	ConsulTokenParam string `json:"consul-token-param" mapstructure:"consul-token-param"`
+	ACLIDPrefix      string `json:"id-prefix" mapstructure:"id-prefix"`
+	// Environment and Labels override those of the sync for this target
+	Environment string            `json:"environment" mapstructure:"environment"`
+	Labels      map[string]string `json:"labels" mapstructure:"labels"`
+}
+
+// SyncTargetsInput is the input for the SyncTargets function
+type SyncTargetsInput struct {
+	// Sync holds the settings shared by all targets, its ACLIDPrefix
+	// is used for targets that don't set their own
+	Sync             *SyncInput
+	Targets          []Target
+	ConsulTokenParam string
+	ContinueOnError  bool
+}
+
+// TargetResult is the outcome of synchronizing a single target
+type TargetResult struct {
+	Target string
+	Err    error
+}
+
+// SyncTargets synchronizes ACLs from a single set of definitions to each target
+func (c *ClientSet) SyncTargets(i *SyncTargetsInput) ([]TargetResult, error) {
+	var results []TargetResult
+	for _, t := range i.Targets {
+		name := t.Name
+		if name == "" {
+			name = t.Address
+		}
+		log.Infof("Synchronizing target %s", name)
+
+		err := c.syncTarget(t, i)
+		results = append(results, TargetResult{Target: name, Err: err})
+		if err != nil && !i.ContinueOnError {
+			return results, errors.Wrapf(err, "Failed to synchronize target %s", name)
+		}
+	}
+	return results, nil
+}
+
+// syncTarget is a helper for SyncTargets and synchronizes a single target
+func (c *ClientSet) syncTarget(t Target, i *SyncTargetsInput) error {
+	tc := *c
+	if t.Address != "" {
+		tc.consulInput.Address = t.Address
+	}
+	if t.Datacenter != "" {
+		tc.consulInput.Datacenter = t.Datacenter
+	}
+
+	tokenParam := t.ConsulTokenParam
+	if tokenParam == "" {
+		tokenParam = i.ConsulTokenParam
+	}
+	if tokenParam == "" {
+		return errors.New("No management token parameter given")
+	}
+	if err := tc.useTokenParam(tokenParam); err != nil {
+		return err
+	}
+
+	if _, err := tc.Consul.Agent().Self(); err != nil {
+		return errors.Wrap(err, "Consul is unreachable")
+	}
+
+	syncInput := *i.Sync
+	if t.ACLIDPrefix != "" {
+		syncInput.ACLIDPrefix = t.ACLIDPrefix
+	}
+	if t.Environment != "" {
+		syncInput.Environment = t.Environment
+	}
+	if t.Labels != nil {
+		syncInput.TargetLabels = t.Labels
+	}
+	return tc.Sync(&syncInput)
+}
+
+// GetTargetsParameter reads a list of targets from a JSON-encoded SSM parameter
+func (c *ClientSet) GetTargetsParameter(name string) ([]Target, error) {
+	val, err := c.getStringParameter(c.definitions, name, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get targets from SSM parameter \"%s\"", name)
+	}
+	var targets []Target
+	if err := json.Unmarshal([]byte(*val), &targets); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse targets from SSM parameter \"%s\"", name)
+	}
+	return targets, nil
+}
@@ -0,0 +1,109 @@
+package acl
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateAccess limits what templated definitions may read, anyone able
+// to write a definition could otherwise read any parameter or variable
+type TemplateAccess struct {
+	// SSMPrefixes are the SSM heirarchies the ssm function may read
+	SSMPrefixes []string
+	// Env are the environment variables the env function may read
+	Env []string
+}
+
+// templateRenderer renders the templated fields of ACL definitions
+type templateRenderer struct {
+	c           *ClientSet
+	vars        map[string]string
+	environment string
+	datacenter  func() (string, error)
+	access      TemplateAccess
+	ssmCache    map[string]string
+}
+
+// newTemplateRenderer creates a templateRenderer for a single sync
+func (c *ClientSet) newTemplateRenderer(vars map[string]string, environment string, datacenter func() (string, error), access TemplateAccess) *templateRenderer {
+	return &templateRenderer{
+		c:           c,
+		vars:        vars,
+		environment: environment,
+		datacenter:  datacenter,
+		access:      access,
+		ssmCache:    make(map[string]string),
+	}
+}
+
+// render renders the Name and Rules of an ACL definition in place
+func (r *templateRenderer) render(acl *aclItem) error {
+	var err error
+	if acl.Name, err = r.renderString(acl.slug+".Name", acl.Name); err != nil {
+		return err
+	}
+	if acl.Rules, err = r.renderString(acl.slug+".Rules", acl.Rules); err != nil {
+		return err
+	}
+	return nil
+}
+
+// renderString renders a single template, strings without
+// template actions are returned unchanged
+func (r *templateRenderer) renderString(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"ssm":         r.ssm,
+		"env":         r.env,
+		"environment": func() string { return r.environment },
+		"datacenter":  r.datacenter,
+	}).Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to parse template %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.vars); err != nil {
+		return "", errors.Wrapf(err, "Failed to render template %s", name)
+	}
+	return buf.String(), nil
+}
+
+// ssm is the template function returning the value of an SSM parameter
+func (r *templateRenderer) ssm(name string) (string, error) {
+	if val, ok := r.ssmCache[name]; ok {
+		return val, nil
+	}
+	allowed := false
+	for _, prefix := range r.access.SSMPrefixes {
+		if prefix != "" && name == path.Clean(name) && underPrefix(name, strings.TrimSuffix(prefix, "/")) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", errors.Errorf("SSM parameter \"%s\" is not under an allowed template prefix", name)
+	}
+	val, err := r.c.getStringParameter(r.c.definitions, name, true)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get SSM parameter \"%s\"", name)
+	}
+	r.ssmCache[name] = *val
+	return *val, nil
+}
+
+// env is the template function returning the value of an allowed environment variable
+func (r *templateRenderer) env(name string) (string, error) {
+	if !contains(r.access.Env, name) {
+		return "", errors.Errorf("Environment variable \"%s\" is not allowed in templates", name)
+	}
+	return os.Getenv(name), nil
+}
@@ -0,0 +1,272 @@
+package acl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SeverityError is the severity of findings that fail validation
+	SeverityError = "error"
+
+	// SeverityWarning is the severity of findings that don't fail validation
+	SeverityWarning = "warning"
+)
+
+// ValidateInput is the input for the Validate and ValidateDirectory functions
+type ValidateInput struct {
+	// ACLDefinitionPrefix is read from SSM unless Directory is given
+	ACLDefinitionPrefix string
+	Directory           string
+	PageSize            int64
+	// ACLIDPrefix is optionally checked for the IDs of definitions marked for destruction
+	ACLIDPrefix string
+	// DeterministicIDs is set when sync derives missing IDs from an ID key
+	DeterministicIDs bool
+	// Guardrails are optionally checked as well
+	Guardrails *Guardrails
+}
+
+// Finding is a single problem found with the ACL definitions
+type Finding struct {
+	Severity  string `json:"severity"`
+	Parameter string `json:"parameter"`
+	Slug      string `json:"slug,omitempty"`
+	Check     string `json:"check"`
+	Message   string `json:"message"`
+}
+
+// HasErrors returns whether any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks ACL definitions in SSM or a directory without touching Consul
+func (c *ClientSet) Validate(i *ValidateInput) ([]Finding, error) {
+	prefix, params, err := c.validateParameters(i)
+	if err != nil {
+		return nil, err
+	}
+
+	var idExists func(string) (bool, error)
+	if aclIDPrefix := ensureTrailingSlash(i.ACLIDPrefix); aclIDPrefix != "" {
+		idExists = func(slug string) (bool, error) {
+			val, err := c.getStringParameter(c.ids, aclIDPrefix+slug, false)
+			if err != nil {
+				return false, errors.Wrapf(err, "Failed to get ACL ID from SSM parameter \"%s\"", aclIDPrefix+slug)
+			}
+			return *val != "", nil
+		}
+	}
+
+	return validateDefinitions(prefix, params, i, idExists)
+}
+
+// ValidateDirectory checks ACL definitions in a directory without AWS or Consul access
+func ValidateDirectory(i *ValidateInput) ([]Finding, error) {
+	prefix, params, err := readDefinitionDirectory(i.Directory)
+	if err != nil {
+		return nil, err
+	}
+	return validateDefinitions(prefix, params, i, nil)
+}
+
+// validateParameters is a helper for Validate and reads the definitions to check
+func (c *ClientSet) validateParameters(i *ValidateInput) (string, []*ssm.Parameter, error) {
+	if i.Directory != "" {
+		return readDefinitionDirectory(i.Directory)
+	}
+	prefix := ensureTrailingSlash(i.ACLDefinitionPrefix)
+	if prefix == "" {
+		return "", nil, errors.New("ACLDefinitionPrefix or Directory is required")
+	}
+	params, err := c.getDefinitionParameters(prefix, i.PageSize)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Failed to get ACL definition parameters from prefix \"%s\"", prefix)
+	}
+	return prefix, params, nil
+}
+
+// validateDefinitions is a helper for Validate and checks a set of definition parameters
+func validateDefinitions(prefix string, params []*ssm.Parameter, i *ValidateInput, idExists func(string) (bool, error)) ([]Finding, error) {
+	var findings []Finding
+	add := func(severity, param, slug, check, message string) {
+		findings = append(findings, Finding{
+			Severity:  severity,
+			Parameter: param,
+			Slug:      slug,
+			Check:     check,
+			Message:   message,
+		})
+	}
+
+	// invalid defaults would stop the library from loading, report and drop them
+	var valid []*ssm.Parameter
+	for _, param := range params {
+		if path.Base(*param.Name) == defaultsName {
+			var d aclDefaults
+			if err := json.Unmarshal([]byte(*param.Value), &d); err != nil {
+				add(SeverityError, *param.Name, "", "syntax", err.Error())
+				continue
+			}
+		}
+		valid = append(valid, param)
+	}
+
+	lib, err := newDefinitionLibrary(prefix, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, rules := range lib.fragments {
+		if strings.Contains(rules, "{{") {
+			continue
+		}
+		if _, err := parseRules(rules); err != nil {
+			add(SeverityError, prefix+fragmentsDir+"/"+name, "", "rules", errors.Cause(err).Error())
+		}
+	}
+
+	slugs := make(map[string]string)
+	ids := make(map[string]string)
+	names := make(map[string]string)
+	var acls []*aclItem
+
+	for _, param := range lib.definitions {
+		acl, err := lib.parseDefinition(param)
+		if err != nil {
+			add(SeverityError, *param.Name, "", "definition", err.Error())
+			continue
+		}
+		acls = append(acls, acl)
+
+		if other, ok := slugs[acl.slug]; ok {
+			add(SeverityError, *param.Name, acl.slug, "duplicate-slug", "Slug is also used by "+other)
+		} else {
+			slugs[acl.slug] = *param.Name
+		}
+
+		if acl.ID != "" {
+			if other, ok := ids[acl.ID]; ok {
+				add(SeverityError, *param.Name, acl.slug, "duplicate-id", "ID is also used by "+other)
+			} else {
+				ids[acl.ID] = *param.Name
+			}
+		}
+
+		if acl.Type != "client" && acl.Type != "management" {
+			add(SeverityError, *param.Name, acl.slug, "type", "Type must be \"client\" or \"management\", got \""+acl.Type+"\"")
+		}
+
+		if acl.ExpiresAt != "" {
+			if _, err := parseExpiresAt(acl.ExpiresAt); err != nil {
+				add(SeverityError, *param.Name, acl.slug, "expiry", "ExpiresAt must be an RFC 3339 timestamp: "+err.Error())
+			}
+		}
+		if acl.TTL != "" {
+			if _, err := parseTTL(acl.TTL); err != nil {
+				add(SeverityError, *param.Name, acl.slug, "expiry", "Invalid TTL: "+errors.Cause(err).Error())
+			}
+		}
+
+		if acl.Destroy {
+			if acl.ID != "" || i.DeterministicIDs {
+				continue
+			}
+			found := false
+			if idExists != nil {
+				if found, err = idExists(acl.slug); err != nil {
+					return nil, err
+				}
+			}
+			if !found {
+				add(SeverityError, *param.Name, acl.slug, "destroy-id", "Marked for destruction but no ID is known")
+			}
+			continue
+		}
+
+		if strings.HasPrefix(acl.Name, breakGlassNamePrefix) {
+			add(SeverityError, *param.Name, acl.slug, "reserved-name", "Names starting with \""+breakGlassNamePrefix+"\" are reserved for break-glass ACLs")
+		}
+
+		if acl.Name != "" && !strings.Contains(acl.Name, "{{") {
+			if other, ok := names[acl.Name]; ok {
+				add(SeverityWarning, *param.Name, acl.slug, "duplicate-name", "Name is also used by "+other)
+			} else {
+				names[acl.Name] = *param.Name
+			}
+		}
+
+		if strings.Contains(acl.Rules, "{{") {
+			add(SeverityWarning, *param.Name, acl.slug, "rules", "Rules are templated and were not checked")
+		} else if _, err := parseRules(acl.Rules); err != nil {
+			add(SeverityError, *param.Name, acl.slug, "rules", errors.Cause(err).Error())
+		}
+	}
+
+	if i.Guardrails != nil {
+		findings = append(findings, i.Guardrails.check(acls, false)...)
+	}
+
+	sort.SliceStable(findings, func(a, b int) bool {
+		return findings[a].Parameter < findings[b].Parameter
+	})
+	return findings, nil
+}
+
+// readDefinitionDirectory reads definitions laid out as files the same way
+// as parameters under a prefix, file extensions are dropped from the names
+func readDefinitionDirectory(dir string) (string, []*ssm.Parameter, error) {
+	dir = filepath.Clean(dir)
+	prefix := ensureTrailingSlash(filepath.ToSlash(dir))
+	var params []*ssm.Parameter
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && file != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := prefix + filepath.ToSlash(rel)
+		name = strings.TrimSuffix(name, path.Ext(name))
+		params = append(params, &ssm.Parameter{
+			Name:  aws.String(name),
+			Value: aws.String(string(data)),
+		})
+		return nil
+	})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Failed to read ACL definitions from directory \"%s\"", dir)
+	}
+	return prefix, params, nil
+}
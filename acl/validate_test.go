@@ -0,0 +1,178 @@
+package acl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefinitions(t *testing.T) {
+	cases := []struct {
+		name     string
+		params   map[string]string
+		input    ValidateInput
+		idExists func(string) (bool, error)
+		// want lists the findings as SEVERITY:PARAMETER:CHECK, in order
+		want []string
+	}{
+		{
+			name: "valid",
+			params: map[string]string{
+				"_fragments/read-kv": `key "" { policy = "read" }`,
+				"web":                `{"Name": "Web", "Include": ["read-kv"]}`,
+			},
+		},
+		{
+			name: "duplicate slug",
+			params: map[string]string{
+				"a/web": `{"Name": "Web A"}`,
+				"b/web": `{"Name": "Web B"}`,
+			},
+			want: []string{"error:b/web:duplicate-slug"},
+		},
+		{
+			name: "duplicate id",
+			params: map[string]string{
+				"api": `{"Name": "API", "ID": "11111111-2222-3333-4444-555555555555"}`,
+				"web": `{"Name": "Web", "ID": "11111111-2222-3333-4444-555555555555"}`,
+			},
+			want: []string{"error:web:duplicate-id"},
+		},
+		{
+			name: "duplicate name",
+			params: map[string]string{
+				"api":   `{"Name": "App"}`,
+				"web":   `{"Name": "App"}`,
+				"tmpl1": `{"Name": "{{ .env }} App"}`,
+				"tmpl2": `{"Name": "{{ .env }} App"}`,
+			},
+			want: []string{"warning:web:duplicate-name"},
+		},
+		{
+			name: "invalid fields",
+			params: map[string]string{
+				"bad-type":    `{"Name": "A", "Type": "admin"}`,
+				"bad-expiry":  `{"Name": "B", "ExpiresAt": "tomorrow"}`,
+				"bad-ttl":     `{"Name": "C", "TTL": "-1h"}`,
+				"bad-json":    `{"Name": `,
+				"bad-rules":   `{"Name": "D", "Rules": "key \"\" { policy = \"admin\" }"}`,
+				"break-glass": `{"Name": "Break Glass: expires 2020-01-01T00:00:00Z", "Type": "management"}`,
+				"templated":   `{"Name": "E", "Rules": "key \"{{ .app }}/\" { policy = \"read\" }"}`,
+			},
+			want: []string{
+				"error:bad-expiry:expiry",
+				"error:bad-json:definition",
+				"error:bad-rules:rules",
+				"error:bad-ttl:expiry",
+				"error:bad-type:type",
+				"error:break-glass:reserved-name",
+				"warning:templated:rules",
+			},
+		},
+		{
+			name: "invalid fragment and defaults",
+			params: map[string]string{
+				"_fragments/bad":  `key "" { policy = "admin" }`,
+				"_fragments/tmpl": `key "{{ .app }}" { policy = "read" }`,
+				"team/_defaults":  `{"Type": `,
+				"team/web":        `{"Name": "Web"}`,
+			},
+			want: []string{
+				"error:_fragments/bad:rules",
+				"error:team/_defaults:syntax",
+			},
+		},
+		{
+			name: "destroy without id",
+			params: map[string]string{
+				"gone":      `{"Destroy": "true"}`,
+				"gone-id":   `{"Destroy": "true", "ID": "11111111-2222-3333-4444-555555555555"}`,
+				"gone-name": `{"Destroy": "true", "Name": "Break Glass: expires soon"}`,
+			},
+			want: []string{
+				"error:gone:destroy-id",
+				"error:gone-name:destroy-id",
+			},
+		},
+		{
+			name: "destroy with id parameter",
+			params: map[string]string{
+				"gone":    `{"Destroy": "true"}`,
+				"missing": `{"Destroy": "true"}`,
+			},
+			idExists: func(slug string) (bool, error) {
+				return slug == "gone", nil
+			},
+			want: []string{"error:missing:destroy-id"},
+		},
+		{
+			name: "destroy with deterministic ids",
+			params: map[string]string{
+				"gone": `{"Destroy": "true"}`,
+			},
+			input: ValidateInput{DeterministicIDs: true},
+		},
+	}
+
+	for _, tc := range cases {
+		input := tc.input
+		findings, err := validateDefinitions(testPrefix, testParams(tc.params), &input, tc.idExists)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.Severity+":"+strings.TrimPrefix(f.Parameter, testPrefix)+":"+f.Check)
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: got findings\n%s\nwant\n%s", tc.name, strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+		if HasErrors(findings) != strings.Contains(strings.Join(tc.want, "\n"), "error:") {
+			t.Errorf("%s: HasErrors = %t", tc.name, HasErrors(findings))
+		}
+	}
+}
+
+func TestReadDefinitionDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consulssm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"web.json":              `{"Name": "Web"}`,
+		"team/api.json":         `{"Name": "API"}`,
+		"_fragments/read.hcl":   `key "" { policy = "read" }`,
+		".git/config":           "ignored",
+		".hidden.json":          "ignored",
+		"team/.draft/skip.json": "ignored",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prefix, params, err := readDefinitionDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.ToSlash(dir) + "/"; prefix != want {
+		t.Errorf("prefix = %q, want %q", prefix, want)
+	}
+	var got []string
+	for _, param := range params {
+		got = append(got, strings.TrimPrefix(*param.Name, prefix))
+	}
+	if want := "_fragments/read,team/api,web"; strings.Join(got, ",") != want {
+		t.Errorf("parameters = %s, want %s", strings.Join(got, ","), want)
+	}
+}
@@ -0,0 +1,52 @@
+package acl
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// WaitForConsul polls Consul until the agent API answers, a leader is
+// elected, and the ACL subsystem is ready, or until the timeout elapses
+func (c *ClientSet) WaitForConsul(timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := c.consulReady()
+		if err == nil {
+			log.Debug("Consul is ready")
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return errors.Wrapf(err, "Timed out after %s waiting for Consul", timeout)
+		}
+		log.Infof("Waiting for Consul: %s", err.Error())
+		time.Sleep(interval)
+	}
+}
+
+// consulReady is a helper for WaitForConsul and checks Consul readiness once
+func (c *ClientSet) consulReady() error {
+	if _, err := c.Consul.Agent().Self(); err != nil {
+		return errors.Wrap(err, "agent API not available")
+	}
+
+	leader, err := c.Consul.Status().Leader()
+	if err != nil {
+		return errors.Wrap(err, "failed to get leader")
+	}
+	if leader == "" {
+		return errors.New("no leader elected")
+	}
+
+	// the anonymous token is created once the leader has initialized ACLs
+	entry, _, err := c.Consul.ACL().Info("anonymous", nil)
+	if err != nil {
+		return errors.Wrap(err, "ACL subsystem not available")
+	}
+	if entry == nil {
+		return errors.New("ACL subsystem not initialized")
+	}
+
+	return nil
+}
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/bdclark/consulssm/acl"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -10,11 +8,7 @@ import (
 )
 
 const (
-	agentACLTokenName            = "acl_token"
-	agentACLAgentTokenName       = "acl_agent_token"
-	agentACLAgentMasterTokenName = "acl_agent_master_token"
-	agentACLReplicationTokenName = "acl_replication_token"
-	agentACLTypeKeyName          = "acl-type"
+	agentACLTypeKeyName = "acl-type"
 )
 
 var agentCmd = &cobra.Command{
@@ -29,9 +23,9 @@ var agentACLTokenCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind non-unique flags only when command is executed
 		// https://github.com/spf13/viper/issues/233
-		bindFlag(cmd, ConsulTokenParamFlagName)
+		bindFlag(cmd, ConsulTokenParamFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName)
 
-		viper.Set(agentACLTypeKeyName, agentACLTokenName)
+		viper.Set(agentACLTypeKeyName, acl.AgentACLToken)
 	},
 	Run: agentACLRun,
 }
@@ -42,8 +36,8 @@ var agentACLAgentTokenCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind commonly-named flags only when command is executed
-		bindFlag(cmd, ConsulTokenParamFlagName)
-		viper.Set(agentACLTypeKeyName, agentACLAgentTokenName)
+		bindFlag(cmd, ConsulTokenParamFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName)
+		viper.Set(agentACLTypeKeyName, acl.AgentACLAgentToken)
 	},
 	Run: agentACLRun,
 }
@@ -54,8 +48,8 @@ var agentACLAgentMasterTokenCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind commonly-named flags only when command is executed
-		bindFlag(cmd, ConsulTokenParamFlagName)
-		viper.Set(agentACLTypeKeyName, agentACLAgentMasterTokenName)
+		bindFlag(cmd, ConsulTokenParamFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName)
+		viper.Set(agentACLTypeKeyName, acl.AgentACLAgentMasterToken)
 	},
 	Run: agentACLRun,
 }
@@ -66,8 +60,8 @@ var agentACLReplicationTokenCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind commonly-named flags only when command is executed
-		bindFlag(cmd, ConsulTokenParamFlagName)
-		viper.Set(agentACLTypeKeyName, agentACLReplicationTokenName)
+		bindFlag(cmd, ConsulTokenParamFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName)
+		viper.Set(agentACLTypeKeyName, acl.AgentACLReplicationToken)
 	},
 	Run: agentACLRun,
 }
@@ -79,40 +73,23 @@ func init() {
 	agentCmd.AddCommand(agentACLReplicationTokenCmd)
 
 	agentCmd.PersistentFlags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	addWaitFlags(agentCmd.PersistentFlags())
 }
 
 func agentACLRun(cmd *cobra.Command, args []string) {
 	if viper.GetBool(DebugFlagName) {
 		log.SetLevel(log.DebugLevel)
 	}
-	if viper.GetString(RegionFlagName) != "" {
-		os.Setenv("AWS_REGION", viper.GetString(RegionFlagName))
-	}
 
-	c, err := acl.NewClientSet(&acl.ClientSetInput{
+	c, err := newClientSet(&acl.ClientSetInput{
 		ConsulTokenParam: viper.GetString(ConsulTokenParamFlagName),
 	})
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	waitForConsul(c)
 
-	tokenParam := args[0]
-	token, err := c.GetStringParameter(tokenParam, true)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-
-	switch viper.GetString(agentACLTypeKeyName) {
-	case agentACLTokenName:
-		_, err = c.Consul.Agent().UpdateACLToken(*token, nil)
-	case agentACLAgentTokenName:
-		_, err = c.Consul.Agent().UpdateACLAgentToken(*token, nil)
-	case agentACLAgentMasterTokenName:
-		_, err = c.Consul.Agent().UpdateACLAgentMasterToken(*token, nil)
-	case agentACLReplicationTokenName:
-		_, err = c.Consul.Agent().UpdateACLReplicationToken(*token, nil)
-	}
-	if err != nil {
+	if err := c.UpdateAgentTokenFromParameter(viper.GetString(agentACLTypeKeyName), args[0]); err != nil {
 		log.Fatal(err.Error())
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/bdclark/consulssm/acl"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,6 +15,14 @@ const (
 	// HideBootstrapFlagName is the flag which sets whether
 	// the bootrap token will be hidden from standard output
 	HideBootstrapFlagName = "hide"
+
+	// ResetBootstrapFlagName is the flag which sets whether
+	// a previous ACL bootstrap should be reset
+	ResetBootstrapFlagName = "reset"
+
+	// DataDirFlagName is the flag which sets the
+	// Consul data directory used for bootstrap resets
+	DataDirFlagName = "data-dir"
 )
 
 // command to bootstrap Consul ACLs
@@ -23,44 +32,77 @@ var bootstrapCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind commonly-named flags only when command is executed
 		// https://github.com/spf13/viper/issues/233
-		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName, HideBootstrapFlagName)
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName, HideBootstrapFlagName,
+			ResetBootstrapFlagName, DataDirFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName,
+			EscrowPrefixFlagName, EscrowSharesFlagName, EscrowThresholdFlagName, EscrowKMSKeyIDsFlagName)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if viper.GetBool(DebugFlagName) {
 			log.SetLevel(log.DebugLevel)
 		}
-		if viper.GetString(RegionFlagName) != "" {
-			os.Setenv("AWS_REGION", viper.GetString(RegionFlagName))
-		}
 
 		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
 		if consulTokenParam == "" {
 			usageError(cmd, "SSM parameter name to write Consul bootstrap token ID is required", 1)
 		}
+		reset := viper.GetBool(ResetBootstrapFlagName)
+		dataDir := viper.GetString(DataDirFlagName)
+		if reset && dataDir == "" {
+			usageError(cmd, "Consul data directory is required to reset bootstrap", 1)
+		}
+		escrowInput := escrowInput()
+		checkEscrowInput(cmd, escrowInput)
 
-		c, err := acl.NewClientSet(&acl.ClientSetInput{
+		c, err := newClientSet(&acl.ClientSetInput{
 			KMSKeyID:  viper.GetString(KMSKeyIDFlagName),
-			Overwrite: viper.GetBool(OverwriteFlagName),
+			Overwrite: viper.GetBool(OverwriteFlagName) || reset,
 			Insecure:  viper.GetBool(InsecureFlagName),
 		})
 		if err != nil {
 			bail(err, 1)
 		}
+		waitForConsul(c)
 
-		id, err := c.Bootstrap(consulTokenParam)
+		var id string
+		if reset {
+			id, err = c.BootstrapReset(consulTokenParam, dataDir)
+		} else {
+			id, err = c.Bootstrap(consulTokenParam)
+		}
 
+		switch errors.Cause(err) {
+		case acl.ErrAlreadyBootstrapped:
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(0)
+		case acl.ErrInvalidBootstrapToken:
+			bail(err, 2)
+		}
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
 			if id == "" {
 				os.Exit(1)
-			} else {
-				fmt.Println(id)
-				os.Exit(255)
 			}
+			// the token could not be saved, so it is only printed if escrow fails too
+			if escrowInput != nil {
+				escrowErr := c.Escrow(id, escrowInput)
+				if escrowErr == nil {
+					os.Exit(255)
+				}
+				fmt.Fprintln(os.Stderr, escrowErr.Error())
+			}
+			fmt.Println(id)
+			os.Exit(255)
 		}
-		if !viper.GetBool(HideBootstrapFlagName) {
+		// escrow implies --hide, the token should only be recoverable from the shares
+		if !viper.GetBool(HideBootstrapFlagName) && escrowInput == nil {
 			fmt.Println(id)
 		}
+		if escrowInput != nil {
+			// the token is already saved, so a failed escrow can be retried with escrow split
+			if err := c.Escrow(id, escrowInput); err != nil {
+				bail(err, 3)
+			}
+		}
 	},
 }
 
@@ -70,4 +112,8 @@ func init() {
 	bootstrapCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name to write Consul bootstrap token ID")
 	bootstrapCmd.Flags().BoolP(OverwriteFlagName, "o", false, "Overwrite existing SSM parameter value if it exists")
 	bootstrapCmd.Flags().Bool(HideBootstrapFlagName, false, "Hide bootstrap token from standard output")
+	bootstrapCmd.Flags().Bool(ResetBootstrapFlagName, false, "Reset a previous bootstrap (must run on the leader, implies --overwrite)")
+	bootstrapCmd.Flags().String(DataDirFlagName, "", "Consul data directory to write the bootstrap reset file to")
+	addWaitFlags(bootstrapCmd.Flags())
+	addEscrowFlags(bootstrapCmd.Flags())
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// BreakGlassParamFlagName is the flag which sets the SSM
+	// parameter holding the break-glass token
+	BreakGlassParamFlagName = "break-glass-param"
+
+	// TTLFlagName is the flag which sets how long
+	// a break-glass token is valid
+	TTLFlagName = "ttl"
+
+	// ReasonFlagName is the flag which sets the
+	// reason a break-glass token is requested
+	ReasonFlagName = "reason"
+
+	// RequesterFlagName is the flag which sets who
+	// requested a break-glass token
+	RequesterFlagName = "requester"
+
+	// MaxTTLFlagName is the flag which sets the
+	// longest TTL a break-glass token may have
+	MaxTTLFlagName = "max-ttl"
+)
+
+var breakGlassCmd = &cobra.Command{
+	Use:   "break-glass",
+	Short: "Create a short-lived Consul management token for emergency access",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName,
+			BreakGlassParamFlagName, TTLFlagName, MaxTTLFlagName, ReasonFlagName, RequesterFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		breakGlassParam := viper.GetString(BreakGlassParamFlagName)
+		ttl := viper.GetDuration(TTLFlagName)
+		reason := viper.GetString(ReasonFlagName)
+
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+		if breakGlassParam == "" {
+			usageError(cmd, "SSM parameter for the break-glass token is required", 1)
+		}
+		if ttl <= 0 {
+			usageError(cmd, "A positive TTL is required", 1)
+		}
+		if reason == "" {
+			usageError(cmd, "A reason is required", 1)
+		}
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			ConsulTokenParam: consulTokenParam,
+			KMSKeyID:         viper.GetString(KMSKeyIDFlagName),
+			Insecure:         viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		token, err := c.BreakGlass(&acl.BreakGlassInput{
+			Param:     breakGlassParam,
+			TTL:       ttl,
+			MaxTTL:    viper.GetDuration(MaxTTLFlagName),
+			Reason:    reason,
+			Requester: viper.GetString(RequesterFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Println(token)
+	},
+}
+
+var breakGlassRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Destroy all break-glass tokens",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ConsulTokenParamFlagName, BreakGlassParamFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			ConsulTokenParam: consulTokenParam,
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		n, err := c.RevokeBreakGlass(viper.GetString(BreakGlassParamFlagName), false)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		log.Infof("Revoked %d break-glass ACL(s).", n)
+	},
+}
+
+func init() {
+	breakGlassCmd.AddCommand(breakGlassRevokeCmd)
+
+	breakGlassCmd.PersistentFlags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	breakGlassCmd.PersistentFlags().String(BreakGlassParamFlagName, "", "SSM parameter name for the break-glass token")
+
+	breakGlassCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting the break-glass token")
+	breakGlassCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when writing the break-glass token to SSM")
+	breakGlassCmd.Flags().Duration(TTLFlagName, 0, "How long the break-glass token is valid, e.g. 1h (required)")
+	breakGlassCmd.Flags().Duration(MaxTTLFlagName, 8*time.Hour, "Longest TTL allowed for a break-glass token")
+	breakGlassCmd.Flags().String(ReasonFlagName, "", "Why the break-glass token is needed (required)")
+	breakGlassCmd.Flags().String(RequesterFlagName, "", "Who requested the token (default the caller's AWS identity)")
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultPolicyFlagName is the flag which sets the
+	// acl_default_policy assumed when evaluating rules
+	DefaultPolicyFlagName = "default-policy"
+)
+
+var canCmd = &cobra.Command{
+	Use:   "can SLUG ACCESS RESOURCE",
+	Short: "Check whether an ACL definition grants access to a resource",
+	Long: `Check whether an ACL definition grants access to a resource
+
+ACCESS is read, write or list, and RESOURCE is a resource type and name,
+e.g. key/app/config, service/web, or operator. Exits with code 2 if access is denied.`,
+	Args: cobra.ExactArgs(3),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ACLDefinitionPrefixFlagName, PageSizeFlagName, DirectoryFlagName,
+			DefaultPolicyFlagName, FormatFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		defs := loadDefinitions(cmd)
+		result, err := defs.Can(args[0], &acl.AccessInput{
+			Access:        args[1],
+			Resource:      args[2],
+			DefaultPolicy: viper.GetString(DefaultPolicyFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if viper.GetString(FormatFlagName) == "json" {
+			printJSON(result)
+		} else if result.Allowed {
+			fmt.Printf("allowed: %s\n", result.Reason)
+		} else {
+			fmt.Printf("denied: %s\n", result.Reason)
+		}
+
+		if !result.Allowed {
+			os.Exit(2)
+		}
+	},
+}
+
+var whoCanCmd = &cobra.Command{
+	Use:   "who-can ACCESS RESOURCE",
+	Short: "List the ACL definitions granting access to a resource",
+	Long: `List the ACL definitions granting access to a resource
+
+ACCESS is read, write or list, and RESOURCE is a resource type and name,
+e.g. key/app/config, service/web, or operator.`,
+	Args: cobra.ExactArgs(2),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ACLDefinitionPrefixFlagName, PageSizeFlagName, DirectoryFlagName,
+			DefaultPolicyFlagName, FormatFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		defs := loadDefinitions(cmd)
+		results, err := defs.WhoCan(&acl.AccessInput{
+			Access:        args[0],
+			Resource:      args[1],
+			DefaultPolicy: viper.GetString(DefaultPolicyFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if viper.GetString(FormatFlagName) == "json" {
+			if results == nil {
+				results = []acl.AccessResult{}
+			}
+			printJSON(results)
+			return
+		}
+		for _, r := range results {
+			fmt.Printf("%s\t%s\t%s\t%s\n", r.Slug, r.Name, r.Type, r.Reason)
+		}
+	},
+}
+
+// loadDefinitions reads ACL definitions from the SSM prefix or local directory flags
+func loadDefinitions(cmd *cobra.Command) *acl.Definitions {
+	definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+	dir := viper.GetString(DirectoryFlagName)
+	format := viper.GetString(FormatFlagName)
+
+	if (definitionPrefix == "") == (dir == "") {
+		usageError(cmd, "Either an SSM prefix or a directory of ACL definitions is required", 1)
+	}
+	if format != "text" && format != "json" {
+		usageError(cmd, "Format must be text or json", 1)
+	}
+
+	if dir != "" {
+		defs, err := acl.LoadDefinitionDirectory(dir)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return defs
+	}
+
+	c, err := newClientSet(&acl.ClientSetInput{})
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defs, err := c.LoadDefinitions(definitionPrefix, viper.GetInt64(PageSizeFlagName))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	return defs
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	fmt.Println(string(out))
+}
+
+func init() {
+	for _, c := range []*cobra.Command{canCmd, whoCanCmd} {
+		c.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions")
+		c.Flags().String(DirectoryFlagName, "", "Local directory to read ACL definitions, laid out like the SSM prefix")
+		c.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+		c.Flags().String(DefaultPolicyFlagName, "deny", "Consul acl_default_policy, allow or deny")
+		c.Flags().String(FormatFlagName, "text", "Output format, text or json")
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigFlagName is the flag which sets the path
+	// of a YAML, HCL or JSON configuration file
+	ConfigFlagName = "config"
+
+	// redacted replaces secret values in displayed configuration
+	redacted = "<redacted>"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect consulssm configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [COMMAND]",
+	Short: "Show the effective configuration, optionally as seen by a command",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			target, _, err := cmd.Root().Find(strings.Fields(args[0]))
+			if err != nil || !target.HasParent() {
+				usageError(cmd, fmt.Sprintf("Unknown command \"%s\"", args[0]), 1)
+			}
+			if err := loadConfig(target); err != nil {
+				bail(err, 1)
+			}
+			// bind all of the command's flags so their values and defaults are shown
+			bindAllFlags(target.InheritedFlags())
+			bindAllFlags(target.Flags())
+		}
+
+		settings := viper.AllSettings()
+		redactSettings(settings)
+
+		// without HTML escaping, so redacted values read as <redacted>
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(settings); err != nil {
+			bail(err, 1)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+}
+
+// loadConfig reads the configuration file, if any, applying the
+// settings in the section named after the given command over the
+// file's global settings. Flags and environment variables still
+// take precedence over anything in the file.
+func loadConfig(cmd *cobra.Command) error {
+	path := viper.GetString(ConfigFlagName)
+	if path == "" {
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return errors.Wrapf(err, "Failed to read config file \"%s\"", path)
+	}
+	settings := v.AllSettings()
+
+	// command sections are not settings themselves
+	for _, c := range cmd.Root().Commands() {
+		delete(settings, c.Name())
+	}
+	if section := commandSection(cmd); section != "" {
+		for k, val := range sectionSettings(v.Get(section)) {
+			settings[strings.ToLower(k)] = val
+		}
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load config file \"%s\"", path)
+	}
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return errors.Wrapf(err, "Failed to load config file \"%s\"", path)
+	}
+	log.Debugf("Using config file \"%s\"", path)
+
+	return nil
+}
+
+// commandSection returns the config file section for a command,
+// which is named after the top-level command it belongs to
+func commandSection(cmd *cobra.Command) string {
+	if !cmd.HasParent() {
+		return ""
+	}
+	for cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	return cmd.Name()
+}
+
+// sectionSettings converts a config file section to a map, whichever
+// form the file format decoded it into
+func sectionSettings(section interface{}) map[string]interface{} {
+	settings := make(map[string]interface{})
+	switch s := section.(type) {
+	case map[string]interface{}:
+		settings = s
+	case map[interface{}]interface{}:
+		for k, v := range s {
+			settings[fmt.Sprint(k)] = v
+		}
+	case []map[string]interface{}:
+		// HCL decodes blocks to a list of maps
+		for _, m := range s {
+			for k, v := range m {
+				settings[k] = v
+			}
+		}
+	}
+	return settings
+}
+
+// redactSettings replaces secret values in settings, including those
+// nested in sections such as consul.token
+func redactSettings(settings map[string]interface{}) {
+	for k, v := range settings {
+		settings[k] = redactSetting(k, v)
+	}
+}
+
+// redactSetting returns a setting's value with secrets redacted. Sections are
+// redacted setting by setting, as are sections in lists, while any other value
+// of a secret setting, a list included, is redacted as a whole.
+func redactSetting(key string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		redactSettings(val)
+		return val
+	case map[interface{}]interface{}:
+		for k, nested := range val {
+			val[k] = redactSetting(fmt.Sprint(k), nested)
+		}
+		return val
+	}
+
+	if isSecretSetting(key) && v != nil && v != "" {
+		return redacted
+	}
+	switch val := v.(type) {
+	case []interface{}:
+		for n, item := range val {
+			val[n] = redactSetting("", item)
+		}
+	case []map[string]interface{}:
+		for _, item := range val {
+			redactSettings(item)
+		}
+	}
+	return v
+}
+
+// isSecretSetting determines whether a setting holds a secret value
+// rather than, for example, the name of an SSM parameter holding one.
+// Only the last part of a nested key such as consul.token is checked.
+func isSecretSetting(key string) bool {
+	key = strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, suffix := range []string{"-param", "-prefix", "-file", "-id", "-ids"} {
+		if strings.HasSuffix(key, suffix) {
+			return false
+		}
+	}
+	for _, word := range []string{"token", "secret", "password", "key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindAllFlags binds every flag in a flag set except help
+func bindAllFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" {
+			viper.BindPFlag(f.Name, f)
+		}
+	})
+}
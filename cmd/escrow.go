@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// EscrowPrefixFlagName is the flag which sets the SSM
+	// heirarchy prefix escrow shares are written under
+	EscrowPrefixFlagName = "escrow-prefix"
+
+	// EscrowSharesFlagName is the flag which sets the number
+	// of shares the management token is split into
+	EscrowSharesFlagName = "escrow-shares"
+
+	// EscrowThresholdFlagName is the flag which sets the number
+	// of shares required to recover the management token
+	EscrowThresholdFlagName = "escrow-threshold"
+
+	// EscrowKMSKeyIDsFlagName is the flag which sets the
+	// KMS key used to encrypt each share
+	EscrowKMSKeyIDsFlagName = "escrow-kms-key-ids"
+
+	// ShareParamFlagName is the flag which sets an SSM
+	// parameter holding an escrow share
+	ShareParamFlagName = "share-param"
+
+	// ShareFlagName is the flag which sets an escrow share
+	ShareFlagName = "share"
+)
+
+var escrowCmd = &cobra.Command{
+	Use:   "escrow",
+	Short: "Split the Consul management token into shares stored in SSM, or recover it",
+}
+
+var escrowSplitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split the Consul management token stored in SSM into escrow shares",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ConsulTokenParamFlagName, EscrowPrefixFlagName, EscrowSharesFlagName,
+			EscrowThresholdFlagName, EscrowKMSKeyIDsFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+		escrowInput := escrowInput()
+		if escrowInput == nil {
+			usageError(cmd, "SSM prefix is required to write escrow shares", 1)
+		}
+
+		c, err := newClientSet(&acl.ClientSetInput{})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if err := c.EscrowFromParameter(consulTokenParam, escrowInput); err != nil {
+			log.Fatal(err.Error())
+		}
+	},
+}
+
+var escrowRecoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Recover the Consul management token from escrow shares",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ShareParamFlagName, ShareFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		recoverInput := &acl.RecoverEscrowInput{
+			Params: viper.GetStringSlice(ShareParamFlagName),
+			Shares: viper.GetStringSlice(ShareFlagName),
+		}
+		if len(recoverInput.Params)+len(recoverInput.Shares) == 0 {
+			usageError(cmd, "Escrow shares are required", 1)
+		}
+
+		c, err := newClientSet(&acl.ClientSetInput{})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		token, err := c.RecoverEscrow(recoverInput)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Println(token)
+	},
+}
+
+// addEscrowFlags adds the flags used by escrowInput to a flag set.
+func addEscrowFlags(flags *pflag.FlagSet) {
+	flags.String(EscrowPrefixFlagName, "", "SSM heirarchy prefix to write management token escrow shares")
+	flags.Int(EscrowSharesFlagName, 5, "Number of escrow shares to split the management token into")
+	flags.Int(EscrowThresholdFlagName, 3, "Number of escrow shares required to recover the management token")
+	flags.StringSlice(EscrowKMSKeyIDsFlagName, nil, "KMS key ID for encrypting each escrow share, one per share (repeatable)")
+}
+
+// escrowInput builds the escrow settings from flags,
+// nil is returned if no escrow prefix is given.
+func escrowInput() *acl.EscrowInput {
+	prefix := viper.GetString(EscrowPrefixFlagName)
+	if prefix == "" {
+		return nil
+	}
+	return &acl.EscrowInput{
+		Prefix:    prefix,
+		Shares:    viper.GetInt(EscrowSharesFlagName),
+		Threshold: viper.GetInt(EscrowThresholdFlagName),
+		KMSKeyIDs: viper.GetStringSlice(EscrowKMSKeyIDsFlagName),
+	}
+}
+
+// checkEscrowInput validates escrow settings before any token is created
+func checkEscrowInput(cmd *cobra.Command, i *acl.EscrowInput) {
+	if i == nil {
+		return
+	}
+	if i.Threshold < 2 || i.Shares < i.Threshold {
+		usageError(cmd, "Escrow threshold must be at least 2 and no more than the number of shares", 1)
+	}
+	if len(i.KMSKeyIDs) != i.Shares {
+		usageError(cmd, "One escrow KMS key ID is required per share", 1)
+	}
+}
+
+func init() {
+	escrowCmd.AddCommand(escrowSplitCmd)
+	escrowCmd.AddCommand(escrowRecoverCmd)
+
+	escrowSplitCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	addEscrowFlags(escrowSplitCmd.Flags())
+
+	escrowRecoverCmd.Flags().StringSlice(ShareParamFlagName, nil, "SSM parameter name holding an escrow share (repeatable)")
+	escrowRecoverCmd.Flags().StringSlice(ShareFlagName, nil, "Escrow share value, e.g. read from SSM by another person (repeatable)")
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// AgentTokenParamFlagName is the flag which sets agent tokens
+	// to install, in the form TYPE=PARAM
+	AgentTokenParamFlagName = "agent-token-param"
+
+	// StateFileFlagName is the flag which sets the file used
+	// to record completed init phases
+	StateFileFlagName = "state-file"
+
+	// initStateFileName is the state file created under
+	// the data directory if no state file is given
+	initStateFileName = "consulssm-init.json"
+)
+
+// command to initialize a cluster's ACLs in one run
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Bootstrap Consul ACLs, sync ACL definitions and install agent tokens",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind commonly-named flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName,
+			ACLDefinitionPrefixFlagName, ACLIDPrefixFlagName, PageSizeFlagName, IDKeyParamFlagName, BreakGlassParamFlagName,
+			AgentTokenParamFlagName, StateFileFlagName, DataDirFlagName, WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName)
+		bindFlag(cmd, targetingFlagNames...)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter name for Consul management token is required", 1)
+		}
+
+		agentTokens := agentTokenParams(cmd)
+
+		stateFile := viper.GetString(StateFileFlagName)
+		if stateFile == "" && viper.GetString(DataDirFlagName) != "" {
+			stateFile = filepath.Join(viper.GetString(DataDirFlagName), initStateFileName)
+		}
+		if stateFile == "" {
+			log.Warn("No state file or data directory given, a rerun will start from the first phase")
+		}
+
+		syncInput := newSyncInput(cmd)
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			KMSKeyID:  viper.GetString(KMSKeyIDFlagName),
+			Overwrite: viper.GetBool(OverwriteFlagName),
+			Insecure:  viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			bail(err, 1)
+		}
+
+		err = c.Init(&acl.InitInput{
+			ConsulTokenParam: consulTokenParam,
+			Sync:             syncInput,
+			AgentTokens:      agentTokens,
+			Wait:             viper.GetBool(WaitFlagName),
+			WaitTimeout:      time.Duration(viper.GetInt64(WaitTimeoutFlagName)) * time.Second,
+			WaitInterval:     time.Duration(viper.GetInt64(WaitIntervalFlagName)) * time.Second,
+			StateFile:        stateFile,
+		})
+		if err != nil {
+			bail(err, 1)
+		}
+	},
+}
+
+// agentTokenParams reads the agent tokens given as TYPE=PARAM
+func agentTokenParams(cmd *cobra.Command) map[string]string {
+	agentTokens := make(map[string]string)
+	for _, v := range viper.GetStringSlice(AgentTokenParamFlagName) {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			usageError(cmd, "Agent tokens must be given as TYPE=PARAM", 1)
+		}
+		agentTokens[parts[0]] = parts[1]
+	}
+	return agentTokens
+}
+
+func init() {
+	initCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting token IDs")
+	initCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when writing token IDs to SSM")
+	initCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	initCmd.Flags().BoolP(OverwriteFlagName, "o", false, "Overwrite existing SSM parameter values if they exist")
+	initCmd.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions")
+	initCmd.Flags().StringP(ACLIDPrefixFlagName, "i", "", "SSM heirarchy prefix to read/write ACL token IDs")
+	initCmd.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+	initCmd.Flags().String(IDKeyParamFlagName, "", "SSM parameter name for secret key used to derive deterministic ACL IDs")
+	initCmd.Flags().String(BreakGlassParamFlagName, "", "SSM parameter name for the break-glass token, deleted once expired")
+	addTargetingFlags(initCmd.Flags())
+	initCmd.Flags().StringSlice(AgentTokenParamFlagName, nil, "Agent token to install as TYPE=PARAM, e.g. acl_agent_token=/ids/agent (repeatable)")
+	initCmd.Flags().String(StateFileFlagName, "", "File recording completed phases, so a rerun resumes after a failed phase (default DATA-DIR/"+initStateFileName+")")
+	initCmd.Flags().String(DataDirFlagName, "", "Consul data directory to keep the init state file in")
+	addWaitFlags(initCmd.Flags())
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConsulConfigFileFlagName is the flag which sets the path
+	// of the Consul configuration file to render a token into
+	ConsulConfigFileFlagName = "consul-config-file"
+
+	// ConsulConfigStyleFlagName is the flag which sets the
+	// Consul configuration style used to render a token
+	ConsulConfigStyleFlagName = "consul-config-style"
+)
+
+// command to pre-generate a Consul management token
+var initTokenCmd = &cobra.Command{
+	Use:   "init-token",
+	Short: "Generate a management token, save it to SSM and render it into Consul configuration",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind commonly-named flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName,
+			ConsulConfigFileFlagName, ConsulConfigStyleFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		configFile := viper.GetString(ConsulConfigFileFlagName)
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter name to write Consul management token is required", 1)
+		}
+		if configFile == "" {
+			usageError(cmd, "Consul configuration file to write is required", 1)
+		}
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			KMSKeyID:  viper.GetString(KMSKeyIDFlagName),
+			Overwrite: viper.GetBool(OverwriteFlagName),
+			Insecure:  viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			bail(err, 1)
+		}
+
+		if _, err := c.InitToken(&acl.InitTokenInput{
+			ConsulTokenParam: consulTokenParam,
+			ConfigFile:       configFile,
+			ConfigStyle:      viper.GetString(ConsulConfigStyleFlagName),
+		}); err != nil {
+			bail(err, 1)
+		}
+	},
+}
+
+func init() {
+	initTokenCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting management token ID")
+	initTokenCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when writing token to SSM")
+	initTokenCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name to write Consul management token ID")
+	initTokenCmd.Flags().BoolP(OverwriteFlagName, "o", false, "Generate a new token even if the SSM parameter exists")
+	initTokenCmd.Flags().StringP(ConsulConfigFileFlagName, "c", "", "Consul configuration file to write the token to (JSON)")
+	initTokenCmd.Flags().String(ConsulConfigStyleFlagName, acl.ConfigStyleLegacy, "Consul configuration style, \"legacy\" (acl_master_token) or \"tokens\" (acl.tokens.initial_management)")
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bdclark/consulssm/acl"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SkipConsulFlagName is the flag which sets whether
+	// the report leaves out checks against Consul
+	SkipConsulFlagName = "skip-consul"
+
+	// OutputFlagName is the flag which sets a file
+	// to write output to instead of stdout
+	OutputFlagName = "output"
+)
+
+const markdownReport = `# Consul ACL Report
+
+Generated {{ .Generated.Format "2006-01-02 15:04:05 MST" }} from ` + "`{{ .Prefix }}`" + `
+
+| Slug | Name | Type | Owner | Rules | ID | In Consul | Expires | Last Modified | Version |
+|------|------|------|-------|-------|----|-----------|---------|---------------|---------|
+{{ range .Entries -}}
+| {{ cell .Slug }} | {{ cell .Name }} | {{ cell .Type }}{{ if .Destroy }} (destroy){{ end }} | {{ cell (owner .) }} | {{ if .Error }}**{{ cell .Error }}**{{ else }}{{ cell (rules .) }}{{ end }} | {{ idSource . }} | {{ inConsul . }} | {{ expires . }} | {{ modified . }} | {{ .Version }} |
+{{ end -}}
+`
+
+const htmlReport = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Consul ACL Report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>Consul ACL Report</h1>
+<p>Generated {{ .Generated.Format "2006-01-02 15:04:05 MST" }} from <code>{{ .Prefix }}</code></p>
+<table>
+<tr><th>Slug</th><th>Name</th><th>Type</th><th>Owner</th><th>Rules</th><th>ID</th><th>In Consul</th><th>Expires</th><th>Last Modified</th><th>Version</th></tr>
+{{ range .Entries -}}
+<tr><td>{{ .Slug }}</td><td>{{ .Name }}</td><td>{{ .Type }}{{ if .Destroy }} (destroy){{ end }}</td><td>{{ owner . }}</td><td>{{ if .Error }}<span class="error">{{ .Error }}</span>{{ else }}{{ rules . }}{{ end }}</td><td>{{ idSource . }}</td><td>{{ inConsul . }}</td><td>{{ expires . }}</td><td>{{ modified . }}</td><td>{{ .Version }}</td></tr>
+{{ end -}}
+</table>
+</body>
+</html>
+`
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate an inventory of ACL definitions",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ConsulTokenParamFlagName, ACLDefinitionPrefixFlagName, ACLIDPrefixFlagName,
+			PageSizeFlagName, SkipConsulFlagName, FormatFlagName, OutputFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+		skipConsul := viper.GetBool(SkipConsulFlagName)
+		format := viper.GetString(FormatFlagName)
+
+		if definitionPrefix == "" {
+			usageError(cmd, "SSM prefix is required to read Consul ACL definitions", 1)
+		}
+		if consulTokenParam == "" && !skipConsul {
+			usageError(cmd, "SSM parameter for Consul management token is required unless Consul is skipped", 1)
+		}
+		if format != "markdown" && format != "html" && format != "json" {
+			usageError(cmd, "Format must be markdown, html or json", 1)
+		}
+
+		clientSetInput := &acl.ClientSetInput{}
+		if !skipConsul {
+			clientSetInput.ConsulTokenParam = consulTokenParam
+		}
+		c, err := newClientSet(clientSetInput)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		report, err := c.Report(&acl.ReportInput{
+			ACLDefinitionPrefix: definitionPrefix,
+			ACLIDPrefix:         viper.GetString(ACLIDPrefixFlagName),
+			PageSize:            viper.GetInt64(PageSizeFlagName),
+			SkipConsul:          skipConsul,
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		out, err := renderReport(report, format)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if output := viper.GetString(OutputFlagName); output != "" {
+			if err := ioutil.WriteFile(output, out, 0644); err != nil {
+				log.Fatalf("Failed to write report to \"%s\": %s", output, err.Error())
+			}
+			log.Infof("Wrote report of %d ACL definition(s) to %s", len(report.Entries), output)
+			return
+		}
+		os.Stdout.Write(out)
+	},
+}
+
+// renderReport renders a report in the given format
+func renderReport(report *acl.Report, format string) ([]byte, error) {
+	funcs := map[string]interface{}{
+		"rules":    reportRules,
+		"idSource": reportIDSource,
+		"inConsul": reportInConsul,
+		"modified": reportModified,
+		"owner":    reportOwner,
+		"expires":  reportExpires,
+		"cell": func(s string) string {
+			return strings.Replace(s, "|", "\\|", -1)
+		},
+	}
+
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "html":
+		t := htmltemplate.Must(htmltemplate.New("report").Funcs(funcs).Parse(htmlReport))
+		err = t.Execute(&b, report)
+	default:
+		t := template.Must(template.New("report").Funcs(funcs).Parse(markdownReport))
+		err = t.Execute(&b, report)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to render report")
+	}
+	return b.Bytes(), nil
+}
+
+// reportRules summarizes an entry's rules on a single line
+func reportRules(e acl.ReportEntry) string {
+	if e.Type == "management" {
+		return "all (management)"
+	}
+	resources := make([]string, 0, len(e.Rules))
+	for r := range e.Rules {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+
+	var parts []string
+	for _, r := range resources {
+		parts = append(parts, r+": "+strings.Join(e.Rules[r], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// reportOwner describes who owns an entry
+func reportOwner(e acl.ReportEntry) string {
+	owner := e.Owner
+	if e.Team != "" {
+		if owner != "" {
+			owner += " "
+		}
+		owner += "(" + e.Team + ")"
+	}
+	if e.Ticket != "" {
+		owner += " " + e.Ticket
+	}
+	return strings.TrimSpace(owner)
+}
+
+// reportIDSource describes where an entry's ID is stored
+func reportIDSource(e acl.ReportEntry) string {
+	switch e.IDSource {
+	case "definition":
+		return "in definition"
+	case "ssm":
+		return "in SSM"
+	default:
+		return "missing"
+	}
+}
+
+// reportInConsul describes whether an entry's ACL exists in Consul
+func reportInConsul(e acl.ReportEntry) string {
+	switch {
+	case e.InConsul == nil:
+		return "not checked"
+	case *e.InConsul:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+// reportExpires describes when an entry expires
+func reportExpires(e acl.ReportEntry) string {
+	if e.Expires == nil {
+		return "never"
+	}
+	expires := e.Expires.UTC().Format("2006-01-02 15:04")
+	if !e.Expires.After(time.Now()) {
+		expires += " (expired)"
+	}
+	return expires
+}
+
+// reportModified describes when and by whom an entry was last modified
+func reportModified(e acl.ReportEntry) string {
+	if e.LastModified.IsZero() {
+		return ""
+	}
+	modified := e.LastModified.UTC().Format("2006-01-02 15:04")
+	if e.LastModifiedUser != "" {
+		modified += " by " + e.LastModifiedUser
+	}
+	return modified
+}
+
+func init() {
+	reportCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	reportCmd.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions (required)")
+	reportCmd.Flags().StringP(ACLIDPrefixFlagName, "i", "", "SSM heirarchy prefix to read ACL token IDs")
+	reportCmd.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+	reportCmd.Flags().Bool(SkipConsulFlagName, false, "Leave out whether ACLs exist in Consul")
+	reportCmd.Flags().String(FormatFlagName, "markdown", "Output format, markdown, html or json")
+	reportCmd.Flags().String(OutputFlagName, "", "File to write the report to instead of stdout")
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// RevokePrefixFlagName is the flag which sets the
+	// prefix of the ID parameters to revoke
+	RevokePrefixFlagName = "prefix"
+)
+
+var revokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Destroy and re-issue Consul ACL tokens whose IDs are stored in SSM",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName,
+			ACLDefinitionPrefixFlagName, ACLIDPrefixFlagName, PageSizeFlagName,
+			RevokePrefixFlagName, RotateAllFlagName, AgentTokenParamFlagName, FormatFlagName)
+		bindFlag(cmd, targetingFlagNames...)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+		idPrefix := viper.GetString(ACLIDPrefixFlagName)
+		prefix := viper.GetString(RevokePrefixFlagName)
+		all := viper.GetBool(RotateAllFlagName)
+		format := viper.GetString(FormatFlagName)
+
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+		if definitionPrefix == "" {
+			usageError(cmd, "SSM prefix is required to read Consul ACL definitions", 1)
+		}
+		if idPrefix == "" {
+			usageError(cmd, "SSM prefix is required to read/write Consul ACL IDs", 1)
+		}
+		if (prefix != "") == all {
+			usageError(cmd, "Either --prefix or --all is required", 1)
+		}
+		if format != "text" && format != "json" {
+			usageError(cmd, "Format must be text or json", 1)
+		}
+		agentTokens := agentTokenParams(cmd)
+		syncInput := newSyncInput(cmd)
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			ConsulTokenParam: consulTokenParam,
+			KMSKeyID:         viper.GetString(KMSKeyIDFlagName),
+			Overwrite:        true,
+			Insecure:         viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		results, revokeErr := c.Revoke(&acl.RevokeInput{
+			Sync:        syncInput,
+			Prefix:      prefix,
+			AgentTokens: agentTokens,
+		})
+
+		if format == "json" {
+			if results == nil {
+				results = []acl.RevokedACL{}
+			}
+			printJSON(results)
+		} else {
+			for _, r := range results {
+				if r.Error != "" {
+					fmt.Printf("%s %s %s -> FAILED: %s\n", r.Slug, r.Parameter, r.OldFingerprint, r.Error)
+				} else if r.Expired {
+					fmt.Printf("%s %s %s -> expired, not re-issued\n", r.Slug, r.Parameter, r.OldFingerprint)
+				} else {
+					fmt.Printf("%s %s %s -> %s (version %d)\n", r.Slug, r.Parameter, r.OldFingerprint, r.NewFingerprint, r.Version)
+				}
+			}
+		}
+
+		if revokeErr != nil {
+			log.Error(revokeErr.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	revokeCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting new token IDs")
+	revokeCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when updating SSM with new token IDs")
+	revokeCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	revokeCmd.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions (required)")
+	revokeCmd.Flags().StringP(ACLIDPrefixFlagName, "i", "", "SSM heirarchy prefix to read/write ACL token IDs (required)")
+	revokeCmd.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+	revokeCmd.Flags().String(RevokePrefixFlagName, "", "Revoke ACLs whose definitions are under this path of the definition prefix, e.g. team-a")
+	revokeCmd.Flags().BoolP(RotateAllFlagName, "a", false, "Revoke all ACLs with IDs stored under the ID prefix")
+	revokeCmd.Flags().StringSlice(AgentTokenParamFlagName, nil, "Agent token to re-install as TYPE=PARAM if PARAM is revoked (repeatable)")
+	addTargetingFlags(revokeCmd.Flags())
+	revokeCmd.Flags().String(FormatFlagName, "text", "Output format, text or json")
+}
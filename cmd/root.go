@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/bdclark/consulssm/acl"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -34,6 +37,102 @@ const (
 	// RegionFlagName is the flag which sets the
 	// AWS Region
 	RegionFlagName = "region"
+
+	// AWSProfileFlagName is the flag which sets the
+	// AWS shared config profile
+	AWSProfileFlagName = "aws-profile"
+
+	// AWSRoleARNFlagName is the flag which sets the
+	// ARN of an IAM role to assume
+	AWSRoleARNFlagName = "aws-role-arn"
+
+	// AWSExternalIDFlagName is the flag which sets the
+	// external ID used when assuming a role
+	AWSExternalIDFlagName = "aws-external-id"
+
+	// AWSSessionNameFlagName is the flag which sets the
+	// session name used when assuming a role
+	AWSSessionNameFlagName = "aws-session-name"
+
+	// SSMEndpointFlagName is the flag which sets a
+	// custom SSM endpoint URL
+	SSMEndpointFlagName = "ssm-endpoint"
+
+	// DefinitionRoleARNFlagName is the flag which sets the IAM
+	// role assumed to read ACL definitions
+	DefinitionRoleARNFlagName = "definition-role-arn"
+
+	// DefinitionRegionFlagName is the flag which sets the AWS
+	// region ACL definitions are read from
+	DefinitionRegionFlagName = "definition-region"
+
+	// IDRoleARNFlagName is the flag which sets the IAM
+	// role assumed to read/write ACL IDs
+	IDRoleARNFlagName = "id-role-arn"
+
+	// IDRegionFlagName is the flag which sets the AWS
+	// region ACL IDs are read from/written to
+	IDRegionFlagName = "id-region"
+
+	// TokenRoleARNFlagName is the flag which sets the IAM role
+	// assumed to read/write the management token parameter
+	TokenRoleARNFlagName = "token-role-arn"
+
+	// TokenRegionFlagName is the flag which sets the AWS region
+	// of the management token parameter
+	TokenRegionFlagName = "token-region"
+
+	// ConsulAddressFlagName is the flag which sets the
+	// address of the Consul agent
+	ConsulAddressFlagName = "consul-address"
+
+	// ConsulSchemeFlagName is the flag which sets the
+	// URI scheme used to talk to Consul
+	ConsulSchemeFlagName = "consul-scheme"
+
+	// ConsulDatacenterFlagName is the flag which sets the
+	// Consul datacenter
+	ConsulDatacenterFlagName = "consul-datacenter"
+
+	// ConsulTokenFileFlagName is the flag which sets a file
+	// containing a Consul token, used if no token parameter is given
+	ConsulTokenFileFlagName = "consul-token-file"
+
+	// ConsulCAFileFlagName is the flag which sets the
+	// CA certificate file used to verify Consul
+	ConsulCAFileFlagName = "consul-ca-file"
+
+	// ConsulCAPathFlagName is the flag which sets the
+	// directory of CA certificates used to verify Consul
+	ConsulCAPathFlagName = "consul-ca-path"
+
+	// ConsulCertFileFlagName is the flag which sets the
+	// client certificate file used to talk to Consul
+	ConsulCertFileFlagName = "consul-cert-file"
+
+	// ConsulKeyFileFlagName is the flag which sets the
+	// client key file used to talk to Consul
+	ConsulKeyFileFlagName = "consul-key-file"
+
+	// ConsulTLSServerNameFlagName is the flag which sets the
+	// server name used to verify Consul's certificate
+	ConsulTLSServerNameFlagName = "consul-tls-server-name"
+
+	// ConsulTLSSkipVerifyFlagName is the flag which sets whether
+	// Consul's certificate should not be verified
+	ConsulTLSSkipVerifyFlagName = "consul-tls-skip-verify"
+
+	// WaitFlagName is the flag which sets whether
+	// to wait for Consul to be ready before proceeding
+	WaitFlagName = "wait"
+
+	// WaitTimeoutFlagName is the flag which sets the maximum
+	// number of seconds to wait for Consul to be ready
+	WaitTimeoutFlagName = "wait-timeout"
+
+	// WaitIntervalFlagName is the flag which sets the number
+	// of seconds between Consul readiness checks
+	WaitIntervalFlagName = "wait-interval"
 )
 
 // Formatter is the struct used in the logging package.
@@ -44,6 +143,11 @@ type Formatter struct {
 var rootCmd = &cobra.Command{
 	Use:   "consulssm",
 	Short: "Bootstrap and manage Consul ACLs via AWS SSM parameters",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := loadConfig(cmd); err != nil {
+			bail(err, 1)
+		}
+	},
 }
 
 func init() {
@@ -52,8 +156,41 @@ func init() {
 
 	rootCmd.PersistentFlags().Bool(DebugFlagName, false, "Enable debug logging")
 	viper.BindPFlag(DebugFlagName, rootCmd.PersistentFlags().Lookup(DebugFlagName))
-	rootCmd.PersistentFlags().String(RegionFlagName, "", "AWS Region")
+	rootCmd.PersistentFlags().String(RegionFlagName, "", "AWS Region (default from environment, profile or instance metadata)")
 	viper.BindPFlag(RegionFlagName, rootCmd.PersistentFlags().Lookup(RegionFlagName))
+	rootCmd.PersistentFlags().String(ConfigFlagName, "", "Config file (YAML, HCL or JSON)")
+	viper.BindPFlag(ConfigFlagName, rootCmd.PersistentFlags().Lookup(ConfigFlagName))
+
+	rootCmd.PersistentFlags().String(AWSProfileFlagName, "", "AWS shared config profile")
+	rootCmd.PersistentFlags().String(AWSRoleARNFlagName, "", "ARN of an IAM role to assume for SSM access")
+	rootCmd.PersistentFlags().String(AWSExternalIDFlagName, "", "External ID used when assuming the IAM role")
+	rootCmd.PersistentFlags().String(AWSSessionNameFlagName, "", "Session name used when assuming the IAM role")
+	rootCmd.PersistentFlags().String(SSMEndpointFlagName, "", "Custom SSM endpoint URL (e.g. a VPC endpoint or LocalStack)")
+	bindPersistentFlag(rootCmd, AWSProfileFlagName, AWSRoleARNFlagName, AWSExternalIDFlagName,
+		AWSSessionNameFlagName, SSMEndpointFlagName)
+
+	rootCmd.PersistentFlags().String(DefinitionRoleARNFlagName, "", "ARN of an IAM role to assume for reading ACL definitions")
+	rootCmd.PersistentFlags().String(DefinitionRegionFlagName, "", "AWS Region to read ACL definitions from")
+	rootCmd.PersistentFlags().String(IDRoleARNFlagName, "", "ARN of an IAM role to assume for reading/writing ACL IDs")
+	rootCmd.PersistentFlags().String(IDRegionFlagName, "", "AWS Region to read/write ACL IDs")
+	rootCmd.PersistentFlags().String(TokenRoleARNFlagName, "", "ARN of an IAM role to assume for the management token parameter")
+	rootCmd.PersistentFlags().String(TokenRegionFlagName, "", "AWS Region of the management token parameter")
+	bindPersistentFlag(rootCmd, DefinitionRoleARNFlagName, DefinitionRegionFlagName, IDRoleARNFlagName,
+		IDRegionFlagName, TokenRoleARNFlagName, TokenRegionFlagName)
+
+	rootCmd.PersistentFlags().String(ConsulAddressFlagName, "", "Consul agent address (default from CONSUL_HTTP_ADDR or 127.0.0.1:8500)")
+	rootCmd.PersistentFlags().String(ConsulSchemeFlagName, "", "Consul URI scheme, http or https")
+	rootCmd.PersistentFlags().String(ConsulDatacenterFlagName, "", "Consul datacenter")
+	rootCmd.PersistentFlags().String(ConsulTokenFileFlagName, "", "File containing a Consul token, used if no token parameter is given")
+	rootCmd.PersistentFlags().String(ConsulCAFileFlagName, "", "CA certificate file to verify Consul")
+	rootCmd.PersistentFlags().String(ConsulCAPathFlagName, "", "Directory of CA certificates to verify Consul")
+	rootCmd.PersistentFlags().String(ConsulCertFileFlagName, "", "Client certificate file for Consul")
+	rootCmd.PersistentFlags().String(ConsulKeyFileFlagName, "", "Client key file for Consul")
+	rootCmd.PersistentFlags().String(ConsulTLSServerNameFlagName, "", "Server name to verify Consul's certificate against")
+	rootCmd.PersistentFlags().Bool(ConsulTLSSkipVerifyFlagName, false, "Skip verification of Consul's certificate")
+	bindPersistentFlag(rootCmd, ConsulAddressFlagName, ConsulSchemeFlagName, ConsulDatacenterFlagName,
+		ConsulTokenFileFlagName, ConsulCAFileFlagName, ConsulCAPathFlagName, ConsulCertFileFlagName,
+		ConsulKeyFileFlagName, ConsulTLSServerNameFlagName, ConsulTLSSkipVerifyFlagName)
 
 	viper.SetEnvPrefix("ssm")
 	viper.AutomaticEnv()
@@ -63,12 +200,20 @@ func init() {
 // Execute is the main entrypoint function into the cli app
 func Execute() {
 	rootCmd.AddCommand(bootstrapCmd)
+	rootCmd.AddCommand(initTokenCmd)
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(agentCmd)
-
-	if os.Getenv("AWS_REGION") == "" {
-		os.Setenv("AWS_REGION", "us-east-1")
-	}
+	rootCmd.AddCommand(rotateCmd)
+	rootCmd.AddCommand(revokeCmd)
+	rootCmd.AddCommand(rotateManagementCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(canCmd)
+	rootCmd.AddCommand(whoCanCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(breakGlassCmd)
+	rootCmd.AddCommand(escrowCmd)
+	rootCmd.AddCommand(configCmd)
 
 	rootCmd.Execute()
 }
@@ -106,6 +251,88 @@ func bindFlag(cmd *cobra.Command, names ...string) {
 	}
 }
 
+func bindPersistentFlag(cmd *cobra.Command, names ...string) {
+	for _, n := range names {
+		viper.BindPFlag(n, cmd.PersistentFlags().Lookup(n))
+	}
+}
+
+// newClientSet creates a client collection, adding the AWS
+// and Consul connection settings from flags to the input.
+func newClientSet(i *acl.ClientSetInput) (*acl.ClientSet, error) {
+	i.AWS = awsInput()
+	i.DefinitionAWS = scopedAWSInput(DefinitionRegionFlagName, DefinitionRoleARNFlagName)
+	i.IDAWS = scopedAWSInput(IDRegionFlagName, IDRoleARNFlagName)
+	i.TokenAWS = scopedAWSInput(TokenRegionFlagName, TokenRoleARNFlagName)
+	i.Consul = consulInput()
+	return acl.NewClientSet(i)
+}
+
+// scopedAWSInput builds the AWS settings for a particular set of
+// parameters, or returns nil if the region and role aren't overridden.
+func scopedAWSInput(regionFlag, roleARNFlag string) *acl.AWSInput {
+	region := viper.GetString(regionFlag)
+	roleARN := viper.GetString(roleARNFlag)
+	if region == "" && roleARN == "" {
+		return nil
+	}
+	i := awsInput()
+	if region != "" {
+		i.Region = region
+	}
+	if roleARN != "" {
+		i.RoleARN = roleARN
+	}
+	return &i
+}
+
+// awsInput builds the AWS settings from flags.
+func awsInput() acl.AWSInput {
+	return acl.AWSInput{
+		Region:      viper.GetString(RegionFlagName),
+		Profile:     viper.GetString(AWSProfileFlagName),
+		RoleARN:     viper.GetString(AWSRoleARNFlagName),
+		ExternalID:  viper.GetString(AWSExternalIDFlagName),
+		SessionName: viper.GetString(AWSSessionNameFlagName),
+		SSMEndpoint: viper.GetString(SSMEndpointFlagName),
+	}
+}
+
+// consulInput builds the Consul connection settings from flags.
+func consulInput() acl.ConsulInput {
+	return acl.ConsulInput{
+		Address:            viper.GetString(ConsulAddressFlagName),
+		Scheme:             viper.GetString(ConsulSchemeFlagName),
+		Datacenter:         viper.GetString(ConsulDatacenterFlagName),
+		TokenFile:          viper.GetString(ConsulTokenFileFlagName),
+		CAFile:             viper.GetString(ConsulCAFileFlagName),
+		CAPath:             viper.GetString(ConsulCAPathFlagName),
+		CertFile:           viper.GetString(ConsulCertFileFlagName),
+		KeyFile:            viper.GetString(ConsulKeyFileFlagName),
+		TLSServerName:      viper.GetString(ConsulTLSServerNameFlagName),
+		InsecureSkipVerify: viper.GetBool(ConsulTLSSkipVerifyFlagName),
+	}
+}
+
+// addWaitFlags adds the flags used by waitForConsul to a flag set.
+func addWaitFlags(flags *pflag.FlagSet) {
+	flags.Bool(WaitFlagName, false, "Wait for Consul agent, leader and ACLs to be ready")
+	flags.Int64(WaitTimeoutFlagName, 60, "Maximum number of seconds to wait for Consul")
+	flags.Int64(WaitIntervalFlagName, 2, "Number of seconds between Consul readiness checks")
+}
+
+// waitForConsul waits for Consul to be ready if requested by flags.
+func waitForConsul(c *acl.ClientSet) {
+	if !viper.GetBool(WaitFlagName) {
+		return
+	}
+	timeout := time.Duration(viper.GetInt64(WaitTimeoutFlagName)) * time.Second
+	interval := time.Duration(viper.GetInt64(WaitIntervalFlagName)) * time.Second
+	if err := c.WaitForConsul(timeout, interval); err != nil {
+		bail(err, 1)
+	}
+}
+
 func bail(err error, code int) {
 	fmt.Fprintln(os.Stderr, err)
 	os.Exit(code)
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// RotateAllFlagName is the flag which sets whether
+	// all ACLs with stored IDs should be rotated
+	RotateAllFlagName = "all"
+
+	// GracePeriodFlagName is the flag which sets the number of
+	// seconds to wait before destroying rotated ACLs
+	GracePeriodFlagName = "grace"
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate [SLUG]",
+	Short: "Rotate Consul ACL tokens whose IDs are stored in SSM",
+	Args:  cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName,
+			ACLDefinitionPrefixFlagName, ACLIDPrefixFlagName, PageSizeFlagName,
+			RotateAllFlagName, GracePeriodFlagName, AgentTokenParamFlagName)
+		bindFlag(cmd, targetingFlagNames...)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+		idPrefix := viper.GetString(ACLIDPrefixFlagName)
+		all := viper.GetBool(RotateAllFlagName)
+
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+		if definitionPrefix == "" {
+			usageError(cmd, "SSM prefix is required to read Consul ACL definitions", 1)
+		}
+		if idPrefix == "" {
+			usageError(cmd, "SSM prefix is required to read/write Consul ACL IDs", 1)
+		}
+		if (len(args) == 1) == all {
+			usageError(cmd, "Either a SLUG or --all is required", 1)
+		}
+		agentTokens := agentTokenParams(cmd)
+		syncInput := newSyncInput(cmd)
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			ConsulTokenParam: consulTokenParam,
+			KMSKeyID:         viper.GetString(KMSKeyIDFlagName),
+			Overwrite:        true,
+			Insecure:         viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		rotateInput := &acl.RotateInput{
+			Sync:        syncInput,
+			AgentTokens: agentTokens,
+			GracePeriod: time.Duration(viper.GetInt64(GracePeriodFlagName)) * time.Second,
+		}
+		if len(args) == 1 {
+			rotateInput.Slug = args[0]
+		}
+
+		if err := c.Rotate(rotateInput); err != nil {
+			log.Fatal(err.Error())
+		}
+	},
+}
+
+func init() {
+	rotateCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting new token IDs")
+	rotateCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when updating SSM with new token IDs")
+	rotateCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	rotateCmd.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions (required)")
+	rotateCmd.Flags().StringP(ACLIDPrefixFlagName, "i", "", "SSM heirarchy prefix to read/write ACL token IDs (required)")
+	rotateCmd.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+	rotateCmd.Flags().BoolP(RotateAllFlagName, "a", false, "Rotate all ACLs with IDs stored under the ID prefix")
+	rotateCmd.Flags().Int64P(GracePeriodFlagName, "g", 0, "Number of seconds to wait before destroying old ACLs")
+	rotateCmd.Flags().StringSlice(AgentTokenParamFlagName, nil, "Agent token to re-install as TYPE=PARAM if PARAM is rotated (repeatable)")
+	addTargetingFlags(rotateCmd.Flags())
+}
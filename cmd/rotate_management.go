@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"github.com/bdclark/consulssm/acl"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ManagementTokenNameFlagName is the flag which sets the
+	// name of a newly created management token
+	ManagementTokenNameFlagName = "name"
+)
+
+// command to rotate the Consul management token
+var rotateManagementCmd = &cobra.Command{
+	Use:   "rotate-management",
+	Short: "Replace the Consul management token stored in an SSM parameter",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind commonly-named flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, ManagementTokenNameFlagName,
+			EscrowPrefixFlagName, EscrowSharesFlagName, EscrowThresholdFlagName, EscrowKMSKeyIDsFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
+		if consulTokenParam == "" {
+			usageError(cmd, "SSM parameter for Consul management token is required", 1)
+		}
+		escrowInput := escrowInput()
+		checkEscrowInput(cmd, escrowInput)
+
+		c, err := newClientSet(&acl.ClientSetInput{
+			ConsulTokenParam: consulTokenParam,
+			KMSKeyID:         viper.GetString(KMSKeyIDFlagName),
+			Overwrite:        true,
+			Insecure:         viper.GetBool(InsecureFlagName),
+		})
+		if err != nil {
+			bail(err, 1)
+		}
+
+		id, err := c.RotateManagement(consulTokenParam, viper.GetString(ManagementTokenNameFlagName))
+		if id == "" {
+			bail(err, 1)
+		}
+		if escrowInput != nil {
+			if escrowErr := c.Escrow(id, escrowInput); escrowErr != nil {
+				log.Error(escrowErr.Error())
+				if err == nil {
+					bail(errors.New("Escrow shares still hold the old management token, retry with escrow split"), 3)
+				}
+			}
+		} else {
+			log.Warn("Escrow shares of the old management token, if any, can no longer recover a valid token. Run escrow split to replace them.")
+		}
+		if err != nil {
+			// new token is in use, but the old one could not be retired
+			bail(err, 255)
+		}
+	},
+}
+
+func init() {
+	rotateManagementCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting management token ID")
+	rotateManagementCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when writing token to SSM")
+	rotateManagementCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
+	rotateManagementCmd.Flags().String(ManagementTokenNameFlagName, "Management Token", "Name of the new management token")
+	addEscrowFlags(rotateManagementCmd.Flags())
+}
@@ -1,14 +1,17 @@
 package cmd
 
 import (
-	"os"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/bdclark/consulssm/acl"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const (
@@ -31,6 +34,54 @@ const (
 	// RecurringFlagName is the flag which sets the number
 	// of seconds between recurring ACL syncs
 	RecurringFlagName = "recurring"
+
+	// IDKeyParamFlagName is the flag which sets the SSM parameter
+	// name storing a secret key used to derive deterministic ACL IDs
+	IDKeyParamFlagName = "id-key-param"
+
+	// TargetsParamFlagName is the flag which sets the SSM parameter
+	// name storing a JSON list of Consul clusters to synchronize
+	TargetsParamFlagName = "targets-param"
+
+	// ContinueOnErrorFlagName is the flag which sets whether
+	// to continue with other targets when one fails
+	ContinueOnErrorFlagName = "continue-on-error"
+
+	// EnvironmentFlagName is the flag which sets the environment
+	// used to select ACL definitions
+	EnvironmentFlagName = "environment"
+
+	// TargetLabelsFlagName is the flag which sets the labels
+	// used to select ACL definitions
+	TargetLabelsFlagName = "target-labels"
+
+	// VarFlagName is the flag which sets a variable
+	// for templated ACL definitions
+	VarFlagName = "var"
+
+	// VarsFileFlagName is the flag which sets a file of
+	// variables for templated ACL definitions
+	VarsFileFlagName = "vars-file"
+
+	// TemplateSSMPrefixFlagName is the flag which sets an SSM
+	// heirarchy templated ACL definitions may read
+	TemplateSSMPrefixFlagName = "template-ssm-prefix"
+
+	// TemplateEnvFlagName is the flag which sets an environment
+	// variable templated ACL definitions may read
+	TemplateEnvFlagName = "template-env"
+
+	// ExpiryWarningFlagName is the flag which sets the number of
+	// hours before expiry to warn about expiring ACLs
+	ExpiryWarningFlagName = "expiry-warning"
+
+	// TargetsConfigKey is the config file key holding
+	// a list of Consul clusters to synchronize
+	TargetsConfigKey = "targets"
+
+	// GuardrailsConfigKey is the config file key holding
+	// the guardrails checked before ACLs are applied
+	GuardrailsConfigKey = "guardrails"
 )
 
 var syncCmd = &cobra.Command{
@@ -39,66 +90,249 @@ var syncCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		// bind non-unique flags only when command is executed
 		// https://github.com/spf13/viper/issues/233
-		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName)
+		bindFlag(cmd, KMSKeyIDFlagName, InsecureFlagName, ConsulTokenParamFlagName, OverwriteFlagName,
+			WaitFlagName, WaitTimeoutFlagName, WaitIntervalFlagName, BreakGlassParamFlagName)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if viper.GetBool(DebugFlagName) {
 			log.SetLevel(log.DebugLevel)
 		}
-		if viper.GetString(RegionFlagName) != "" {
-			os.Setenv("AWS_REGION", viper.GetString(RegionFlagName))
-		}
 
 		consulTokenParam := viper.GetString(ConsulTokenParamFlagName)
 		definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+		targetsParam := viper.GetString(TargetsParamFlagName)
+		continueOnError := viper.GetBool(ContinueOnErrorFlagName)
 
-		if consulTokenParam == "" {
+		var targets []acl.Target
+		if err := viper.UnmarshalKey(TargetsConfigKey, &targets); err != nil {
+			bail(errors.Wrap(err, "Failed to parse targets from config"), 1)
+		}
+		fanOut := len(targets) > 0 || targetsParam != ""
+
+		if consulTokenParam == "" && !fanOut {
 			usageError(cmd, "SSM parameter for Consul management token is required", 1)
 		}
 		if definitionPrefix == "" {
 			usageError(cmd, "SSM prefix is required to read Consul ACL definitions", 1)
 		}
 
-		c, err := acl.NewClientSet(&acl.ClientSetInput{
-			ConsulTokenParam: consulTokenParam,
-			KMSKeyID:         viper.GetString(KMSKeyIDFlagName),
-			Overwrite:        viper.GetBool(OverwriteFlagName),
-			Insecure:         viper.GetBool(InsecureFlagName),
-		})
+		clientSetInput := &acl.ClientSetInput{
+			KMSKeyID:  viper.GetString(KMSKeyIDFlagName),
+			Overwrite: viper.GetBool(OverwriteFlagName),
+			Insecure:  viper.GetBool(InsecureFlagName),
+		}
+		// with targets, the token parameter is only a default for targets
+		if !fanOut {
+			clientSetInput.ConsulTokenParam = consulTokenParam
+		}
+		c, err := newClientSet(clientSetInput)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
 
-		syncInput := &acl.SyncInput{
-			ACLDefinitionPrefix: definitionPrefix,
-			ACLIDPrefix:         viper.GetString(ACLIDPrefixFlagName),
-			PageSize:            viper.GetInt64(PageSizeFlagName),
-			OnlyIfConsulLeader:  viper.GetBool(RequireLeaderFlagName),
+		if targetsParam != "" {
+			t, err := c.GetTargetsParameter(targetsParam)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			targets = append(targets, t...)
+		}
+		if !fanOut {
+			waitForConsul(c)
+		}
+
+		syncInput := newSyncInput(cmd)
+
+		sync := func() error {
+			if !fanOut {
+				return c.Sync(syncInput)
+			}
+			return syncTargets(c, &acl.SyncTargetsInput{
+				Sync:             syncInput,
+				Targets:          targets,
+				ConsulTokenParam: consulTokenParam,
+				ContinueOnError:  continueOnError,
+			})
 		}
 
 		recurring := viper.GetInt64(RecurringFlagName)
 		if recurring > 0 {
 			for {
-				if err := c.Sync(syncInput); err != nil {
-					log.Fatal(err.Error())
+				if err := sync(); err != nil {
+					// failed targets are tried again on the next sync
+					if _, ok := err.(*targetsFailedError); !ok || !continueOnError {
+						log.Fatal(err.Error())
+					}
+					log.Error(err.Error())
 				}
 				time.Sleep(time.Duration(recurring) * time.Second)
 			}
-		} else if err := c.Sync(syncInput); err != nil {
+		} else if err := sync(); err != nil {
 			log.Fatal(err.Error())
 		}
 	},
 }
 
+// newSyncInput builds the sync settings from flags and the config file,
+// shared by every command that syncs definitions so they select and
+// render definitions alike
+func newSyncInput(cmd *cobra.Command) *acl.SyncInput {
+	vars, err := templateVars()
+	if err != nil {
+		bail(err, 1)
+	}
+	g, err := guardrails()
+	if err != nil {
+		bail(err, 1)
+	}
+	return &acl.SyncInput{
+		ACLDefinitionPrefix: viper.GetString(ACLDefinitionPrefixFlagName),
+		ACLIDPrefix:         viper.GetString(ACLIDPrefixFlagName),
+		PageSize:            viper.GetInt64(PageSizeFlagName),
+		OnlyIfConsulLeader:  viper.GetBool(RequireLeaderFlagName),
+		IDKeyParam:          viper.GetString(IDKeyParamFlagName),
+		Environment:         viper.GetString(EnvironmentFlagName),
+		TargetLabels:        targetLabels(cmd),
+		Vars:                vars,
+		TemplateAccess:      templateAccess(),
+		Guardrails:          g,
+		ExpiryWarning:       time.Duration(viper.GetInt64(ExpiryWarningFlagName)) * time.Hour,
+		BreakGlassParam:     viper.GetString(BreakGlassParamFlagName),
+	}
+}
+
+// targetingFlagNames are the flags added by addTargetingFlags
+var targetingFlagNames = []string{EnvironmentFlagName, TargetLabelsFlagName, VarFlagName, VarsFileFlagName,
+	TemplateSSMPrefixFlagName, TemplateEnvFlagName, ExpiryWarningFlagName}
+
+// addTargetingFlags adds the flags which select and render definitions
+// to commands other than sync, which must bind them when executed
+func addTargetingFlags(flags *pflag.FlagSet) {
+	flags.String(EnvironmentFlagName, "", "Environment used to select ACL definitions limited to particular environments")
+	flags.StringSlice(TargetLabelsFlagName, nil, "Labels used to select ACL definitions, as KEY=VALUE (repeatable)")
+	flags.StringSlice(VarFlagName, nil, "Variable for templated ACL definitions, as KEY=VALUE (repeatable)")
+	flags.String(VarsFileFlagName, "", "YAML, HCL or JSON file of variables for templated ACL definitions")
+	flags.StringSlice(TemplateSSMPrefixFlagName, nil, "SSM heirarchy prefix templated ACL definitions may read (repeatable)")
+	flags.StringSlice(TemplateEnvFlagName, nil, "Environment variable templated ACL definitions may read (repeatable)")
+	flags.Int64(ExpiryWarningFlagName, 168, "Number of hours before expiry to warn about expiring ACLs")
+}
+
+// guardrails reads the guardrails from the config file, if any
+func guardrails() (*acl.Guardrails, error) {
+	if !viper.IsSet(GuardrailsConfigKey) {
+		return nil, nil
+	}
+	var g acl.Guardrails
+	if err := viper.UnmarshalKey(GuardrailsConfigKey, &g); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse guardrails from config")
+	}
+	return &g, nil
+}
+
+// templateVars reads the variables for templated definitions from the
+// vars file and flags, with flags taking precedence.
+func templateVars() (map[string]string, error) {
+	vars := make(map[string]string)
+
+	if path := viper.GetString(VarsFileFlagName); path != "" {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, errors.Wrapf(err, "Failed to read vars file \"%s\"", path)
+		}
+		for k, val := range v.AllSettings() {
+			vars[k] = fmt.Sprint(val)
+		}
+	}
+
+	for _, v := range viper.GetStringSlice(VarFlagName) {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Variables must be given as KEY=VALUE, got \"%s\"", v)
+		}
+		vars[strings.ToLower(parts[0])] = parts[1]
+	}
+
+	return vars, nil
+}
+
+// targetLabels reads the labels used to select definitions, given as KEY=VALUE
+func targetLabels(cmd *cobra.Command) map[string]string {
+	labels := make(map[string]string)
+	for _, v := range viper.GetStringSlice(TargetLabelsFlagName) {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			usageError(cmd, "Target labels must be given as KEY=VALUE", 1)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels
+}
+
+// templateAccess reads what templated definitions may read from flags.
+func templateAccess() acl.TemplateAccess {
+	return acl.TemplateAccess{
+		SSMPrefixes: viper.GetStringSlice(TemplateSSMPrefixFlagName),
+		Env:         viper.GetStringSlice(TemplateEnvFlagName),
+	}
+}
+
+// syncTargets synchronizes each target and reports the per-target results.
+func syncTargets(c *acl.ClientSet, i *acl.SyncTargetsInput) error {
+	results, err := c.SyncTargets(i)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			log.Errorf("Target %s: failed: %s", r.Target, r.Err.Error())
+		} else {
+			log.Infof("Target %s: synchronized", r.Target)
+		}
+	}
+	if failed > 0 {
+		return &targetsFailedError{failed: failed, total: len(results)}
+	}
+	return nil
+}
+
+// targetsFailedError is returned by syncTargets when targets failed to synchronize
+type targetsFailedError struct {
+	failed, total int
+}
+
+func (e *targetsFailedError) Error() string {
+	return fmt.Sprintf("%d of %d targets failed to synchronize", e.failed, e.total)
+}
+
 func init() {
 	syncCmd.Flags().StringP(KMSKeyIDFlagName, "k", "", "Optional KMS key ID for encrypting new token IDs")
 	syncCmd.Flags().BoolP(InsecureFlagName, "I", false, "Skip encryption when updating SSM with new token IDs")
 	syncCmd.Flags().StringP(ConsulTokenParamFlagName, "m", "", "SSM parameter name for Consul management token")
 	syncCmd.Flags().BoolP(OverwriteFlagName, "o", false, "Overwrite existing SSM parameter values if they exist")
+	syncCmd.Flags().String(BreakGlassParamFlagName, "", "SSM parameter name for the break-glass token, deleted once expired")
+	addWaitFlags(syncCmd.Flags())
 
 	AddStringFlag(syncCmd, ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions (required)")
 	AddStringFlag(syncCmd, ACLIDPrefixFlagName, "i", "", "SSM heirarchy prefix to read/write ACL token IDs")
 	AddInt64Flag(syncCmd, PageSizeFlagName, "p", 0, "Maximum results per SSM query")
 	AddBoolFlag(syncCmd, RequireLeaderFlagName, "l", false, "Manage ACLs only if Consul agent is current leader")
 	AddInt64Flag(syncCmd, RecurringFlagName, "r", 0, "Make recurring and wait given number of seconds between syncs")
+	AddStringFlag(syncCmd, IDKeyParamFlagName, "", "", "SSM parameter name for secret key used to derive deterministic ACL IDs")
+	AddStringFlag(syncCmd, TargetsParamFlagName, "", "", "SSM parameter name for a JSON list of Consul clusters to synchronize")
+	AddBoolFlag(syncCmd, ContinueOnErrorFlagName, "", false, "Continue with remaining targets when one fails")
+	AddStringFlag(syncCmd, EnvironmentFlagName, "", "", "Environment used to select ACL definitions limited to particular environments")
+	syncCmd.Flags().StringSlice(TargetLabelsFlagName, nil, "Labels used to select ACL definitions, as KEY=VALUE (repeatable)")
+	viper.BindPFlag(TargetLabelsFlagName, syncCmd.Flags().Lookup(TargetLabelsFlagName))
+	syncCmd.Flags().StringSlice(VarFlagName, nil, "Variable for templated ACL definitions, as KEY=VALUE (repeatable)")
+	viper.BindPFlag(VarFlagName, syncCmd.Flags().Lookup(VarFlagName))
+	AddInt64Flag(syncCmd, ExpiryWarningFlagName, "", 168, "Number of hours before expiry to warn about expiring ACLs")
+	AddStringFlag(syncCmd, VarsFileFlagName, "", "", "YAML, HCL or JSON file of variables for templated ACL definitions")
+	syncCmd.Flags().StringSlice(TemplateSSMPrefixFlagName, nil, "SSM heirarchy prefix templated ACL definitions may read (repeatable)")
+	viper.BindPFlag(TemplateSSMPrefixFlagName, syncCmd.Flags().Lookup(TemplateSSMPrefixFlagName))
+	syncCmd.Flags().StringSlice(TemplateEnvFlagName, nil, "Environment variable templated ACL definitions may read (repeatable)")
+	viper.BindPFlag(TemplateEnvFlagName, syncCmd.Flags().Lookup(TemplateEnvFlagName))
 }
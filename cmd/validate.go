@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bdclark/consulssm/acl"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DirectoryFlagName is the flag which sets a local
+	// directory to read ACL definitions from
+	DirectoryFlagName = "dir"
+
+	// FormatFlagName is the flag which sets the output
+	// format of a command
+	FormatFlagName = "format"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate ACL definitions without applying them to Consul",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// bind non-unique flags only when command is executed
+		// https://github.com/spf13/viper/issues/233
+		bindFlag(cmd, ACLDefinitionPrefixFlagName, ACLIDPrefixFlagName, PageSizeFlagName,
+			IDKeyParamFlagName, DirectoryFlagName, FormatFlagName)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool(DebugFlagName) {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		definitionPrefix := viper.GetString(ACLDefinitionPrefixFlagName)
+		dir := viper.GetString(DirectoryFlagName)
+		format := viper.GetString(FormatFlagName)
+
+		if (definitionPrefix == "") == (dir == "") {
+			usageError(cmd, "Either an SSM prefix or a directory of ACL definitions is required", 1)
+		}
+		if format != "text" && format != "json" {
+			usageError(cmd, "Format must be text or json", 1)
+		}
+
+		g, err := guardrails()
+		if err != nil {
+			bail(err, 1)
+		}
+
+		validateInput := &acl.ValidateInput{
+			ACLDefinitionPrefix: definitionPrefix,
+			Directory:           dir,
+			PageSize:            viper.GetInt64(PageSizeFlagName),
+			ACLIDPrefix:         viper.GetString(ACLIDPrefixFlagName),
+			DeterministicIDs:    viper.GetString(IDKeyParamFlagName) != "",
+			Guardrails:          g,
+		}
+
+		var findings []acl.Finding
+		if dir != "" && validateInput.ACLIDPrefix == "" {
+			// a local directory alone needs no AWS access
+			findings, err = acl.ValidateDirectory(validateInput)
+		} else {
+			var c *acl.ClientSet
+			c, err = newClientSet(&acl.ClientSetInput{})
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			findings, err = c.Validate(validateInput)
+		}
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if format == "json" {
+			if findings == nil {
+				findings = []acl.Finding{}
+			}
+			printJSON(findings)
+		} else {
+			for _, f := range findings {
+				fmt.Printf("%s: %s [%s] %s\n", f.Severity, f.Parameter, f.Check, f.Message)
+			}
+		}
+
+		if acl.HasErrors(findings) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringP(ACLDefinitionPrefixFlagName, "d", "", "SSM heirarchy prefix to read ACL definitions")
+	validateCmd.Flags().String(DirectoryFlagName, "", "Local directory to read ACL definitions, laid out like the SSM prefix")
+	validateCmd.Flags().StringP(ACLIDPrefixFlagName, "i", "", "Optional SSM heirarchy prefix to check for the IDs of ACLs marked for destruction")
+	validateCmd.Flags().Int64P(PageSizeFlagName, "p", 0, "Maximum results per SSM query")
+	validateCmd.Flags().String(IDKeyParamFlagName, "", "SSM parameter name for secret key used to derive deterministic ACL IDs")
+	validateCmd.Flags().String(FormatFlagName, "text", "Output format, text or json")
+}
@@ -39,18 +39,10 @@ resource "docker_container" "consul" {
 
   depends_on = ["aws_ssm_parameter.consul_acls"]
 
-  // bootstrap ACL system
-  provisioner "local-exec" {
-    command = "sleep 5 && consulssm bootstrap --overwrite || true"
-
-    environment {
-      SSM_CONSUL_TOKEN_PARAM = "${var.consul_bootstrap_token_param}"
-    }
-  }
-
-  // sync ACLs from SSM
+  // bootstrap ACL system, sync ACLs from SSM and
+  // install a token from SSM to local agent
   provisioner "local-exec" {
-    command = "consulssm sync --overwrite"
+    command = "consulssm init --overwrite --wait --agent-token-param acl_agent_token=${var.consul_acl_id_prefix}/agent"
 
     environment {
       SSM_CONSUL_TOKEN_PARAM = "${var.consul_bootstrap_token_param}"
@@ -58,13 +50,4 @@ resource "docker_container" "consul" {
       SSM_ID_PREFIX          = "${var.consul_acl_id_prefix}"
     }
   }
-
-  // install a token from SSM to local agent
-  provisioner "local-exec" {
-    command = "consulssm agent acl_agent_token ${var.consul_acl_id_prefix}/agent"
-
-    environment {
-      SSM_CONSUL_TOKEN_PARAM = "${var.consul_bootstrap_token_param}"
-    }
-  }
 }